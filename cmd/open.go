@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/acristoffers/tmux-tui/tmux_tui"
+	"github.com/spf13/cobra"
+)
+
+var OpenCmd = &cobra.Command{
+	Use:   "open [DIRECTORY]",
+	Short: "Opens the session of a project directory",
+	Long: fmt.Sprintf(`Opens the session of a project directory, the current one by default.
+
+The session that starts in the directory, or that is named after it, is
+reused. Otherwise a session named after the directory is created, from the
+template in %s at its root if there is one, in which the name and root can be
+left out. Then the client switches to it, or attaches to it when not
+running inside tmux.`, tmux_tui.ProjectConfigName),
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		detached, err := cmd.Flags().GetBool("detached")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not parse options: %s\n", err)
+			os.Exit(1)
+		}
+
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		client := tmux_tui.NewExecClient()
+		session, err := tmux_tui.OpenDirectory(client, dir)
+		if session == -1 {
+			fmt.Fprintf(os.Stderr, "Could not open %s: %s\n", dir, err)
+			os.Exit(1)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not rank %s up: %s\n", dir, err)
+		}
+
+		target := fmt.Sprintf("$%d", session)
+		switch {
+		case detached:
+		case len(os.Getenv("TMUX")) > 0:
+			if err := client.SwitchClient(target); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not switch to the session: %s\n", err)
+				os.Exit(1)
+			}
+		default:
+			attach := exec.Command("tmux", "attach-session", "-t", target)
+			attach.Stdin, attach.Stdout, attach.Stderr = os.Stdin, os.Stdout, os.Stderr
+			if err := attach.Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not attach to the session: %s\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	OpenCmd.Flags().BoolP("detached", "d", false, "Does not switch to the session.")
+	RootCmd.AddCommand(OpenCmd)
+}
@@ -64,12 +64,34 @@ var RootCmd = &cobra.Command{
 			return
 		}
 
+		configPath, err := cmd.Flags().GetString("config")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not parse options: %s\n", err)
+			os.Exit(1)
+		}
+
+		config, err := tmux_tui.LoadConfig(configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load the configuration: %s\n", err)
+			os.Exit(1)
+		}
+
+		options, err := config.Options()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load the configuration: %s\n", err)
+			os.Exit(1)
+		}
+
 		themeHandle, err := cmd.Flags().GetString("theme")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Could not parse options: %s\n", err)
 			os.Exit(1)
 		}
 
+		if !cmd.Flags().Changed("theme") && len(config.Theme) > 0 {
+			themeHandle = config.Theme
+		}
+
 		theme, err := tmux_tui.ThemeForName(themeHandle)
 		if err != nil {
 			bytes, err := os.ReadFile(themeHandle)
@@ -88,13 +110,37 @@ var RootCmd = &cobra.Command{
 			}
 		}
 
+		confirm, err := cmd.Flags().GetString("confirm")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not parse options: %s\n", err)
+			os.Exit(1)
+		}
+
+		if cmd.Flags().Changed("confirm") {
+			options.ConfirmPolicy, err = tmux_tui.ParseConfirmPolicy(confirm)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Could not parse options: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		searchHistory, err := cmd.Flags().GetInt("search-history")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not parse options: %s\n", err)
+			os.Exit(1)
+		}
+
+		if cmd.Flags().Changed("search-history") {
+			options.SearchHistory = searchHistory
+		}
+
 		originalBackgroundColor := termenv.DefaultOutput().BackgroundColor()
 		defer termenv.DefaultOutput().SetBackgroundColor(originalBackgroundColor)
 
 		termenvBackgroundColor := termenv.ColorProfile().Color(string(theme.Background))
 		termenv.DefaultOutput().SetBackgroundColor(termenvBackgroundColor)
 
-		p := tmux_tui.NewApplication(theme)
+		p := tmux_tui.NewApplication(theme, options)
 		m, err := p.Run()
 		if err != nil {
 			os.Stderr.WriteString(fmt.Sprintf("There's been an error: %v\n", err))
@@ -103,6 +149,7 @@ var RootCmd = &cobra.Command{
 
 		switch m := m.(type) {
 		case tmux_tui.AppModel:
+			m.Close()
 			if len(m.Error) != 0 {
 				os.Stderr.WriteString(m.Error + "\n")
 				os.Exit(1)
@@ -122,5 +169,8 @@ func init() {
 	RootCmd.Flags().Bool("list-themes", false, "Lists available themes.")
 	RootCmd.Flags().BoolP("version", "v", false, "Prints the version.")
 	RootCmd.Flags().String("dump-theme", "", "Prints the YAML version a builtin theme.")
+	RootCmd.Flags().String("config", tmux_tui.ConfigPath(), "Reads the configuration from this file.")
 	RootCmd.Flags().StringP("theme", "t", "dracula", "Selects a theme. Default: dracula.")
+	RootCmd.Flags().String("confirm", "busy", "When to ask before deleting: busy (not an idle shell), always or never. Default: busy.")
+	RootCmd.Flags().Int("search-history", 1000, "Lines of scrollback searched in each pane by F, -1 for all. Default: 1000.")
 }
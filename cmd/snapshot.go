@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/acristoffers/tmux-tui/tmux_tui"
+	"github.com/spf13/cobra"
+)
+
+var SaveCmd = &cobra.Command{
+	Use:   "save [FILE]",
+	Short: "Saves all sessions, windows and panes to a file",
+	Long: fmt.Sprintf(`Saves all sessions, windows and panes to a file, so they can be recreated with restore.
+
+The file is written as JSON if its name ends in .json and as YAML otherwise.
+Default: %s`, tmux_tui.DefaultSnapshotPath()),
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scrollback, err := cmd.Flags().GetInt("scrollback")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not parse options: %s\n", err)
+			os.Exit(1)
+		}
+
+		path := tmux_tui.DefaultSnapshotPath()
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		snapshot, err := tmux_tui.TakeSnapshot(tmux_tui.NewExecClient(), scrollback)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not take snapshot: %s\n", err)
+			os.Exit(1)
+		}
+
+		if err := tmux_tui.SaveSnapshot(path, snapshot); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not save snapshot: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var RestoreCmd = &cobra.Command{
+	Use:   "restore [FILE]",
+	Short: "Recreates the sessions saved in a file",
+	Long: fmt.Sprintf(`Recreates the sessions saved in a file by save. Sessions that already exist are skipped.
+
+Default: %s`, tmux_tui.DefaultSnapshotPath()),
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := tmux_tui.DefaultSnapshotPath()
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		snapshot, err := tmux_tui.LoadSnapshot(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load snapshot: %s\n", err)
+			os.Exit(1)
+		}
+
+		skipped, err := tmux_tui.RestoreSnapshot(tmux_tui.NewExecClient(), snapshot)
+		for _, name := range skipped {
+			fmt.Printf("Skipped %s, a session with that name already exists.\n", name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not restore everything:\n%s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	SaveCmd.Flags().IntP("scrollback", "s", 0, "Lines of scrollback to save for each pane. -1 saves all of it.")
+	RootCmd.AddCommand(SaveCmd, RestoreCmd)
+}
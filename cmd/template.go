@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/acristoffers/tmux-tui/tmux_tui"
+	"github.com/spf13/cobra"
+)
+
+var TemplateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Creates sessions from YAML templates",
+	Long: fmt.Sprintf(`Creates sessions from YAML templates.
+
+Templates are looked up by name in %s, or can be given as a path.
+A template looks like this:
+
+  name: project
+  root: ~/src/project
+  environment:
+    EDITOR: nvim
+  windows:
+    - name: editor
+      panes:
+        - command: nvim
+    - name: server
+      layout: main-vertical
+      panes:
+        - command: make run
+        - root: web
+          split: horizontal
+          command: npm run dev`, tmux_tui.TemplatesDir()),
+}
+
+var TemplateApplyCmd = &cobra.Command{
+	Use:   "apply TEMPLATE",
+	Short: "Creates the session described by a template",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		detached, err := cmd.Flags().GetBool("detached")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not parse options: %s\n", err)
+			os.Exit(1)
+		}
+
+		template, err := tmux_tui.FindTemplate(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+
+		client := tmux_tui.NewExecClient()
+		session, err := tmux_tui.ApplyTemplate(client, template)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not apply template: %s\n", err)
+			os.Exit(1)
+		}
+
+		if !detached && len(os.Getenv("TMUX")) > 0 {
+			if err := client.SwitchClient(fmt.Sprintf("$%d", session)); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not switch to the new session: %s\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+var TemplateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the available templates",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		templates, err := tmux_tui.ListTemplates()
+		for _, template := range templates {
+			fmt.Printf("%20s %d windows\n", template.Name, max(len(template.Windows), 1))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	TemplateApplyCmd.Flags().BoolP("detached", "d", false, "Does not switch to the new session.")
+	TemplateCmd.AddCommand(TemplateApplyCmd, TemplateListCmd)
+	RootCmd.AddCommand(TemplateCmd)
+}
@@ -1,33 +1,25 @@
 package tmux_tui
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"os/exec"
-	"slices"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
 )
 
 type (
-	errorMsg          string
+	errorMsg          struct{ err error }
+	infoMsg           string
 	previewMsg        string
 	tickMsg           time.Time
 	clearInputTextMsg struct{}
 
-	listEntitiesMsg struct {
-		sessions       []TmuxEntity
-		windows        []TmuxEntity
-		panes          []TmuxEntity
-		currentSession int
-		currentWindow  int
-		currentPane    int
-	}
+	listEntitiesMsg Entities
 )
 
 const (
@@ -37,6 +29,14 @@ const (
 	NewWindow
 	RenameSession
 	RenameWindow
+	NameLayout
+	SearchScrollback
+	SearchPanes
+	SendCommand
+	SendKeys
+	SessionDirectory
+	WindowDirectory
+	OpenProject
 )
 
 type (
@@ -45,19 +45,47 @@ type (
 		height int
 	}
 
-	TmuxEntity struct {
-		id     int
-		name   string
-		parent int
-	}
-
 	InputAction int
 
+	// Options are the user's preferences.
+	Options struct {
+		ConfirmPolicy ConfirmPolicy
+		// SearchHistory is how many lines of scrollback searching all panes
+		// looks at, -1 for all of it.
+		SearchHistory int
+		// RefreshInterval is how often the lists are refreshed when tmux's
+		// control mode is not available.
+		RefreshInterval time.Duration
+		// StartupFrame is the list focused at startup, from 1 to 3.
+		StartupFrame int
+		ShowAll      bool
+		TreeView     bool
+		Arrangement  Arrangement
+		// PreviewHeight and PreviewWidth are the percentage of the screen
+		// the preview takes on top of the lists or on their right.
+		PreviewHeight int
+		PreviewWidth  int
+		// ProjectRoots are looked into for git repositories, down to
+		// ProjectDepth levels, when picking a start directory.
+		ProjectRoots []string
+		ProjectDepth int
+		Keys         KeyMap
+	}
+
 	AppModel struct {
 		Error string
 
 		terminal terminal
 		theme    Theme
+		client   TmuxClient
+		options  Options
+
+		subscription    Subscription
+		followedSession int
+		// previewPending is set for a while after the preview is captured,
+		// and previewStale when it has to be captured again after that
+		previewPending bool
+		previewStale   bool
 
 		preview  Frame
 		sessions ListFrame
@@ -67,155 +95,492 @@ type (
 		focusedFrame int
 
 		showAll bool
-		swapSrc int
+		// treeView shows the tree instead of the three lists
+		treeView   bool
+		treeFilter string
+		collapsed  map[string]bool
+		// arrangement is how the preview and the lists share the screen
+		arrangement Arrangement
+		swapSrc     int
+		// moveSrcs are the windows or panes being moved, from moveFrame
+		moveSrcs  []int
+		moveFrame int
+		// dragSrc is the window being dragged with the mouse, -1 if none
+		dragSrc   int
+		lastClick click
+
+		errors              []errorEntry
+		notification        string
+		notificationIsError bool
+		showErrors          bool
+		showHelp            bool
+		helpOffset          int
 
 		textInput   textinput.Model
 		inputAction InputAction
-		filter      string
+		// newName is the name of the session or window whose start
+		// directory is being picked from candidates
+		newName     string
+		candidates  []string
+		directories []DirectoryEntry
+		projects    []string
+
+		picker       Picker
+		pickerAction PickerAction
+		templates    []Template
+		layouts      []SavedLayout
+
+		layoutMode   bool
+		presetLayout int
+
+		scrollback *Scrollback
+		search     *GlobalSearch
+
+		confirmation *Confirmation
+		undo         []undoEntry
 	}
 )
 
-func NewApplication(theme Theme) *tea.Program {
+func DefaultOptions() Options {
+	return Options{
+		ConfirmPolicy:   ConfirmBusy,
+		SearchHistory:   1000,
+		RefreshInterval: time.Second,
+		StartupFrame:    1,
+		Arrangement:     ArrangeAuto,
+		PreviewHeight:   60,
+		PreviewWidth:    60,
+		ProjectDepth:    3,
+		Keys:            DefaultKeyMap,
+	}
+}
+
+func NewApplication(theme Theme, options Options) *tea.Program {
+	return tea.NewProgram(NewAppModel(theme, NewExecClient(), options), tea.WithAltScreen(), tea.WithMouseCellMotion())
+}
+
+// NewAppModel creates the application model on top of any TmuxClient, which
+// allows driving it without a tmux server.
+func NewAppModel(theme Theme, client TmuxClient, options Options) AppModel {
+	defaults := DefaultOptions()
+	if len(options.ConfirmPolicy) == 0 {
+		options.ConfirmPolicy = defaults.ConfirmPolicy
+	}
+	if options.RefreshInterval <= 0 {
+		options.RefreshInterval = defaults.RefreshInterval
+	}
+	if options.StartupFrame < 1 || options.StartupFrame > 3 {
+		options.StartupFrame = defaults.StartupFrame
+	}
+	if len(options.Arrangement) == 0 {
+		options.Arrangement = defaults.Arrangement
+	}
+	if options.PreviewHeight <= 0 || options.PreviewHeight >= 100 {
+		options.PreviewHeight = defaults.PreviewHeight
+	}
+	if options.PreviewWidth <= 0 || options.PreviewWidth >= 100 {
+		options.PreviewWidth = defaults.PreviewWidth
+	}
+	if options.ProjectDepth <= 0 {
+		options.ProjectDepth = defaults.ProjectDepth
+	}
+	if options.Keys == nil {
+		options.Keys = defaults.Keys
+	}
+
 	model := AppModel{
-		Error:        "",
-		terminal:     terminal{80, 80},
-		theme:        theme,
-		preview:      Frame{title: "Preview"},
-		sessions:     ListFrame{frame: Frame{title: "[1] Sessions", focused: true}, parentId: -1},
-		windows:      ListFrame{frame: Frame{title: "[2] Windows"}, parentId: -1},
-		panes:        ListFrame{frame: Frame{title: "[3] Panes"}, parentId: -1},
-		focusedFrame: 1,
-		showAll:      false,
-		swapSrc:      -1,
-		inputAction:  None,
+		Error:           "",
+		terminal:        terminal{80, 80},
+		theme:           theme,
+		client:          client,
+		options:         options,
+		preview:         Frame{title: "[0] Preview"},
+		sessions:        ListFrame{frame: Frame{title: "[1] Sessions"}, parentId: -1},
+		windows:         ListFrame{frame: Frame{title: "[2] Windows"}, parentId: -1},
+		panes:           ListFrame{frame: Frame{title: "[3] Panes"}, parentId: -1},
+		showAll:         options.ShowAll,
+		treeView:        options.TreeView,
+		collapsed:       map[string]bool{},
+		arrangement:     options.Arrangement,
+		swapSrc:         -1,
+		dragSrc:         -1,
+		followedSession: -1,
+		inputAction:     None,
 	}
 
+	model.focus(options.StartupFrame)
+
 	model.textInput = textinput.New()
 	model.textInput.Focus()
 	model.textInput.TextStyle = lipgloss.NewStyle().Foreground(theme.Foreground).Background(theme.Background)
 
-	return tea.NewProgram(model, tea.WithAltScreen())
+	return model
 }
 
 func (m AppModel) Init() tea.Cmd {
-	return tea.Batch(tickCmd(), listEntitiesCmd)
+	return tea.Batch(subscribeCmd(m), listEntitiesCmd(m))
+}
+
+// Close releases the control mode connection, if there is one.
+func (m AppModel) Close() {
+	if m.subscription != nil {
+		m.subscription.Close()
+	}
+}
+
+// focus gives focus to frame.
+func (m *AppModel) focus(frame int) {
+	m.focusedFrame = frame
+	m.sessions.frame.focused = frame == 1
+	m.windows.frame.focused = frame == 2
+	m.panes.frame.focused = frame == 3
+}
+
+// moveSelection moves the selection of the focused list, or the cursor of
+// the tree, by delta items.
+func (m *AppModel) moveSelection(delta int) {
+	if m.treeView {
+		m.moveTreeCursor(delta)
+	} else {
+		m.focusedList().Move(delta)
+	}
+}
+
+// pageSize is how many rows of the focused list, or the tree, are in view.
+func (m AppModel) pageSize() int {
+	if m.treeView {
+		return m.treeHeight()
+	}
+	return max(1, m.focusedList().rows)
+}
+
+// rowCount is how many rows the focused list, or the tree, has.
+func (m AppModel) rowCount() int {
+	if m.treeView {
+		return len(m.treeRows())
+	}
+	return len(m.focusedList().visibleItems())
+}
+
+// endMove leaves move mode.
+func (m *AppModel) endMove() {
+	m.list(m.moveFrame).ClearMarks()
+	m.moveSrcs = nil
 }
 
 func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd = nil
 
+	if m.showErrors {
+		goto errors_mode
+	}
+
+	if m.showHelp {
+		goto help_mode
+	}
+
+	if m.confirmation != nil {
+		goto confirm_mode
+	}
+
 	if m.swapSrc != -1 {
 		goto swap_mode
 	}
 
+	if len(m.moveSrcs) > 0 {
+		goto move_mode
+	}
+
+	if m.pickerAction != PickNothing {
+		goto picker_mode
+	}
+
 	if m.inputAction != None {
 		goto input_mode
 	}
 
+	if m.search != nil {
+		goto search_mode
+	}
+
+	if m.scrollback != nil {
+		goto scrollback_mode
+	}
+
+	if m.layoutMode {
+		goto layout_mode
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case tea.KeyEsc.String():
-			cmd = tea.Quit
-		case "1":
-			m.focusedFrame = 1
-			m.sessions.frame.focused = true
-			m.windows.frame.focused = false
-			m.panes.frame.focused = false
-			cmd = previewCmd(m)
-		case "2":
-			m.focusedFrame = 2
-			m.sessions.frame.focused = false
-			m.windows.frame.focused = true
-			m.panes.frame.focused = false
-			cmd = previewCmd(m)
-		case "3":
-			m.focusedFrame = 3
-			m.sessions.frame.focused = false
-			m.windows.frame.focused = false
-			m.panes.frame.focused = true
-			cmd = previewCmd(m)
-		case tea.KeyEnter.String():
-			switch m.focusedFrame {
-			case 1:
-				cmd = goToSessionCmd(m)
-			case 2:
-				cmd = goToWindowCmd(m)
-			case 3:
-				cmd = goToPaneCmd(m)
+		switch m.options.Keys.Action(NormalMode, msg) {
+		case "back":
+			if len(m.focusedList().markedIds) > 0 {
+				m.focusedList().ClearMarks()
+			} else {
+				cmd = tea.Quit
 			}
-		case "d":
-			switch m.focusedFrame {
-			case 1:
-				cmd = deleteSessionCmd(m)
-			case 2:
-				cmd = deleteWindowCmd(m)
-			case 3:
-				cmd = deletePaneCmd(m)
+		case "scrollback":
+			cmd = m.openScrollback()
+		case "focus-sessions":
+			m.focus(1)
+			cmd = m.refreshPreview()
+		case "focus-windows":
+			m.focus(2)
+			cmd = m.refreshPreview()
+		case "focus-panes":
+			m.focus(3)
+			cmd = m.refreshPreview()
+		case "go-to":
+			cmd = m.goToCmd(m.focusedFrame)
+		case "delete":
+			if targets := m.focusedList().Targets(); len(targets) > 0 {
+				cmd = planDeleteCmd(m, m.focusedFrame, targets)
 			}
-		case "h":
+		case "undo":
+			if len(m.undo) > 0 {
+				entry := m.undo[len(m.undo)-1]
+				m.undo = m.undo[:len(m.undo)-1]
+				cmd = tea.Sequence(undoCmd(m, entry), listEntitiesCmd(m))
+			}
+		case "mark":
+			m.focusedList().ToggleMark()
+			m.moveSelection(1)
+		case "mark-all":
+			m.focusedList().MarkVisible()
+		case "invert-marks":
+			m.focusedList().InvertMarks()
+		case "split-horizontal":
 			if m.focusedFrame == 3 {
 				cmd = splitPane(m, true)
 			}
-		case "v":
+		case "split-vertical":
 			if m.focusedFrame == 3 {
 				cmd = splitPane(m, false)
 			}
-		case "r":
+		case "rename":
 			switch m.focusedFrame {
 			case 1:
-				m.inputAction = RenameSession
-				m.textInput.SetValue(m.sessions.ItemWithId(m.sessions.currentId).name)
-				m.textInput.SetCursor(100)
+				if session := m.sessions.ItemWithId(m.sessions.currentId); session != nil {
+					m.inputAction = RenameSession
+					m.textInput.SetValue(session.name)
+					m.textInput.SetCursor(100)
+				}
 			case 2:
-				m.inputAction = RenameWindow
-				m.textInput.SetValue(m.windows.ItemWithId(m.windows.currentId).name)
-				m.textInput.SetCursor(100)
+				if window := m.windows.ItemWithId(m.windows.currentId); window != nil {
+					m.inputAction = RenameWindow
+					m.textInput.SetValue(window.name)
+					m.textInput.SetCursor(100)
+				}
 			}
-		case "n":
+		case "new":
 			m.textInput.SetValue("")
 			switch m.focusedFrame {
 			case 1:
-				m.inputAction = NewSession
+				templates, err := ListTemplates()
+				if err != nil {
+					m.pushError(err)
+				}
+				if len(templates) == 0 {
+					m.inputAction = NewSession
+					break
+				}
+				m.templates = templates
+				m.pickerAction = PickTemplate
+				m.picker = Picker{title: "New session from template"}
+				m.picker.items = append(m.picker.items, PickerItem{"Empty session", ""})
+				for _, template := range templates {
+					m.picker.items = append(m.picker.items, PickerItem{template.Name, template.Root})
+				}
 			case 2:
 				m.inputAction = NewWindow
 			}
-		case "N":
+		case "new-nameless":
 			switch m.focusedFrame {
 			case 1:
-				cmd = newSessionCmd(m)
+				cmd = newSessionCmd(m, "", "")
 			case 2:
-				cmd = newWindowCmd(m)
+				cmd = newWindowCmd(m, "", "")
 			}
-		case "/":
+		case "open":
+			cmd = m.askDirectory(OpenProject, "")
+		case "filter":
 			m.inputAction = Filter
-			m.textInput.SetValue(m.filter)
+			m.textInput.SetValue(formatFilterQuery(m.filters(), m.filterFrame()))
 			m.textInput.SetCursor(100)
-		case "s":
+		case "swap":
 			switch m.focusedFrame {
 			case 2:
 				m.swapSrc = m.windows.currentId
+				m.windows.ClearMarks()
 				m.windows.MarkSelection()
 			case 3:
 				m.swapSrc = m.panes.currentId
+				m.panes.ClearMarks()
 				m.panes.MarkSelection()
 			}
+		case "move":
+			// The destination is picked from the frame above the source
+			switch {
+			case (m.focusedFrame == 2 || m.focusedFrame == 3) && len(m.focusedList().Targets()) > 0:
+				m.moveFrame = m.focusedFrame
+				m.moveSrcs = m.focusedList().Targets()
+				if len(m.focusedList().markedIds) == 0 {
+					m.focusedList().MarkSelection()
+				}
+				m.focus(m.focusedFrame - 1)
+			}
+		case "move-up":
+			if m.focusedFrame == 2 {
+				cmd = shiftWindowCmd(m, -1)
+			}
+		case "move-down":
+			if m.focusedFrame == 2 {
+				cmd = shiftWindowCmd(m, 1)
+			}
+		case "renumber":
+			if m.focusedFrame != 3 {
+				cmd = renumberWindowsCmd(m)
+			}
+		case "layout":
+			if m.focusedFrame != 1 {
+				m.layoutMode = true
+				m.focus(3)
+			}
+		case "break-pane":
+			if m.focusedFrame == 3 {
+				cmd = breakPaneCmd(m)
+			}
+		case "search-panes":
+			m.inputAction = SearchPanes
+			m.textInput.SetValue("")
+		case "send-command":
+			m.inputAction = SendCommand
+			m.textInput.SetValue("")
+		case "send-keys":
+			m.inputAction = SendKeys
+			m.textInput.SetValue("")
+		case "synchronize":
+			if m.focusedFrame != 1 {
+				cmd = toggleSynchronizeCmd(m)
+			}
+		case "snapshot":
+			cmd = saveSnapshotCmd(m)
+		case "dismiss":
+			m.notification = ""
+		case "errors":
+			m.showErrors = true
+		case "help":
+			m.showHelp = true
+		case "tree":
+			m.treeView = !m.treeView
+			if !m.treeView {
+				m.treeFilter = ""
+			}
+		case "arrange":
+			m.arrangement = m.nextArrangement()
+			m.pushInfo("Arrangement: " + m.describeArrangement())
+			cmd = m.refreshPreview()
+		case "collapse":
+			if m.treeView {
+				m.setCollapsed(true)
+			}
+		case "expand":
+			if m.treeView {
+				m.setCollapsed(false)
+			}
 		}
+	case tea.MouseMsg:
+		cmd = m.handleMouse(msg)
 	case clearInputTextMsg:
 		m.textInput.SetValue("")
-		cmd = listEntitiesCmd
+		cmd = listEntitiesCmd(m)
 	}
 	goto common_bindings
 
+errors_mode:
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch m.options.Keys.Action(ErrorsMode, msg) {
+		case "close":
+			m.showErrors = false
+			m.notification = ""
+		case "quit":
+			cmd = tea.Quit
+		}
+	}
+	goto basic_handlers
+
+help_mode:
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch m.options.Keys.Action(HelpMode, msg) {
+		case "close":
+			m.showHelp = false
+			m.helpOffset = 0
+		case "down":
+			m.helpOffset = max(0, min(m.helpOffset+1, len(m.helpLines())-(m.terminal.height-2)))
+		case "up":
+			m.helpOffset = max(0, m.helpOffset-1)
+		case "quit":
+			cmd = tea.Quit
+		}
+	}
+	goto basic_handlers
+
+picker_mode:
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch m.options.Keys.Action(PickerMode, msg) {
+		case "cancel":
+			m.pickerAction = PickNothing
+		case "up":
+			m.picker.SelectPrevious()
+		case "down":
+			m.picker.SelectNext()
+		case "select":
+			switch m.pickerAction {
+			case PickTemplate:
+				if m.picker.cursor == 0 {
+					m.inputAction = NewSession
+				} else {
+					cmd = applyTemplateCmd(m, m.templates[m.picker.cursor-1])
+				}
+			case PickLayout:
+				cmd = selectLayoutCmd(m, m.layouts[m.picker.cursor].Layout)
+			}
+			m.pickerAction = PickNothing
+		case "quit":
+			cmd = tea.Quit
+		}
+	}
+	goto basic_handlers
+
+confirm_mode:
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch m.options.Keys.Action(ConfirmMode, msg) {
+		case "yes":
+			cmd = m.confirmation.onConfirm
+			m.list(m.confirmation.frame).ClearMarks()
+			m.confirmation = nil
+		case "no":
+			m.confirmation = nil
+		case "quit":
+			cmd = tea.Quit
+		}
+	}
+	goto basic_handlers
+
 swap_mode:
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case tea.KeyEsc.String():
+		switch m.options.Keys.Action(SwapMode, msg) {
+		case "cancel":
 			m.swapSrc = -1
 			m.windows.ClearMarks()
 			m.panes.ClearMarks()
-		case "s", tea.KeySpace.String(), tea.KeyEnter.String():
+		case "swap":
 			m.windows.ClearMarks()
 			m.panes.ClearMarks()
 			switch m.focusedFrame {
@@ -229,42 +594,263 @@ swap_mode:
 	}
 	goto common_bindings
 
+move_mode:
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch action := m.options.Keys.Action(MoveMode, msg); action {
+		case "cancel":
+			// Keeps the marks, to try again somewhere else
+			m.focus(m.moveFrame)
+			m.moveSrcs = nil
+		case "focus-sessions", "focus-windows", "focus-panes":
+			m.focus(focusFrames[action])
+			cmd = m.refreshPreview()
+		case "move", "link", "join-horizontal", "join-vertical":
+			switch {
+			case m.moveFrame == 2 && m.focusedFrame == 1 && (action == "move" || action == "link"):
+				cmd = moveWindowCmd(m, m.moveSrcs, action == "link")
+				m.endMove()
+			case m.moveFrame == 3 && m.focusedFrame != 1 && action != "link":
+				cmd = joinPaneCmd(m, m.moveSrcs, action == "join-horizontal")
+				m.endMove()
+			}
+		}
+	}
+	goto common_bindings
+
+search_mode:
+	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		cmd = m.handleMouse(msg)
+	case tea.KeyMsg:
+		_, viewHeight := m.previewSize()
+		switch m.options.Keys.Action(SearchMode, msg) {
+		case "back":
+			m.search = nil
+		case "down":
+			m.search.Move(1, viewHeight)
+		case "up":
+			m.search.Move(-1, viewHeight)
+		case "half-page-down":
+			m.search.Move(viewHeight/2, viewHeight)
+		case "half-page-up":
+			m.search.Move(-viewHeight/2, viewHeight)
+		case "go-to":
+			if result := m.search.Selected(); result != nil && m.panes.ItemWithId(result.pane) != nil {
+				m.panes.currentId = result.pane
+				cmd = goToPaneCmd(m)
+			}
+		case "search":
+			m.inputAction = SearchPanes
+			m.textInput.SetValue(m.search.query)
+			m.textInput.SetCursor(100)
+		case "dismiss":
+			m.notification = ""
+		case "help":
+			m.showHelp = true
+		case "quit":
+			cmd = tea.Quit
+		}
+	}
+	goto basic_handlers
+
+scrollback_mode:
+	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		cmd = m.handleMouse(msg)
+	case tea.KeyMsg:
+		_, viewHeight := m.previewSize()
+		scrollback := m.scrollback
+		switch action := m.options.Keys.Action(ScrollbackMode, msg); action {
+		case "back":
+			if scrollback.anchor != -1 {
+				scrollback.anchor = -1
+			} else if scrollback.pattern != nil {
+				scrollback.Search(nil)
+			} else {
+				m.scrollback = nil
+			}
+		case "focus-sessions", "focus-windows", "focus-panes":
+			m.scrollback = nil
+			m.focus(focusFrames[action])
+			cmd = m.refreshPreview()
+		case "down":
+			scrollback.Move(1, viewHeight)
+		case "up":
+			scrollback.Move(-1, viewHeight)
+		case "half-page-down":
+			scrollback.Move(viewHeight/2, viewHeight)
+		case "half-page-up":
+			scrollback.Move(-viewHeight/2, viewHeight)
+		case "page-down":
+			scrollback.Move(viewHeight, viewHeight)
+		case "page-up":
+			scrollback.Move(-viewHeight, viewHeight)
+		case "top":
+			scrollback.Move(-len(scrollback.lines), viewHeight)
+		case "bottom":
+			scrollback.Move(len(scrollback.lines), viewHeight)
+		case "search":
+			m.inputAction = SearchScrollback
+			m.textInput.SetValue("")
+			cmd = scrollback.allCmd(m)
+		case "next-match":
+			scrollback.NextMatch(false, viewHeight)
+		case "previous-match":
+			scrollback.NextMatch(true, viewHeight)
+		case "select":
+			scrollback.ToggleSelection()
+		case "copy":
+			cmd = copySelectionCmd(m, scrollback.Selection())
+			scrollback.anchor = -1
+		case "dismiss":
+			m.notification = ""
+		case "help":
+			m.showHelp = true
+		case "quit":
+			cmd = tea.Quit
+		}
+		if m.scrollback != nil && cmd == nil && scrollback.NearTop(viewHeight) {
+			cmd = scrollback.olderCmd(m)
+		}
+	}
+	goto basic_handlers
+
+layout_mode:
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch m.options.Keys.Action(LayoutMode, msg) {
+		case "back":
+			m.layoutMode = false
+		case "grow-left":
+			cmd = resizePaneCmd(m, "L")
+		case "grow-down":
+			cmd = resizePaneCmd(m, "D")
+		case "grow-up":
+			cmd = resizePaneCmd(m, "U")
+		case "grow-right":
+			cmd = resizePaneCmd(m, "R")
+		case "next-preset":
+			m.presetLayout = (m.presetLayout + 1) % len(PresetLayouts)
+			cmd = selectLayoutCmd(m, PresetLayouts[m.presetLayout])
+		case "zoom":
+			cmd = toggleZoomCmd(m)
+		case "dismiss":
+			m.notification = ""
+		case "help":
+			m.showHelp = true
+		case "save":
+			m.inputAction = NameLayout
+			m.textInput.SetValue("")
+		case "load":
+			layouts, err := LoadLayouts()
+			if err != nil {
+				m.pushError(err)
+				break
+			}
+			if len(layouts) == 0 {
+				m.pushInfo("No saved layouts. Save one with " + m.options.Keys.Key(LayoutMode, "save"))
+				break
+			}
+			m.layouts = layouts
+			m.pickerAction = PickLayout
+			m.picker = Picker{title: "Saved layouts"}
+			for _, layout := range layouts {
+				m.picker.items = append(m.picker.items, PickerItem{layout.Name, layout.Layout})
+			}
+		}
+	}
+	goto common_bindings
+
 input_mode:
 	m.textInput, cmd = m.textInput.Update(msg)
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case tea.KeyEsc.String():
+		action := m.inputAction
+		switch m.options.Keys.Action(InputMode, msg) {
+		case "cancel":
 			if m.inputAction == Filter {
-				m.filter = ""
-				m.sessions.filterText = ""
-				m.windows.filterText = ""
-				m.panes.filterText = ""
+				m.setFilters(nil)
+			}
+			if m.inputAction == SearchScrollback {
+				m.scrollback.Search(nil)
 			}
 			m.inputAction = None
 			m.textInput.SetValue("")
-		case tea.KeyEnter.String():
+		case "up":
+			m.picker.SelectPrevious()
+		case "down":
+			m.picker.SelectNext()
+		case "complete":
+			if m.pickingDirectory() {
+				m.completeDirectory()
+			}
+		case "accept":
 			switch m.inputAction {
 			case NewSession:
-				cmd = newSessionCmd(m)
+				cmd = m.askDirectory(SessionDirectory, m.textInput.Value())
+			case SessionDirectory, WindowDirectory:
+				directory, err := m.selectedDirectory()
+				if err != nil {
+					m.pushError(err)
+				} else if m.inputAction == SessionDirectory {
+					cmd = newSessionCmd(m, m.newName, directory)
+				} else {
+					cmd = newWindowCmd(m, m.newName, directory)
+				}
+				m.textInput.SetValue("")
+			case OpenProject:
+				if directory, err := m.selectedDirectory(); err != nil {
+					m.pushError(err)
+				} else if len(directory) > 0 {
+					cmd = openDirectoryCmd(m, directory)
+				}
+				m.textInput.SetValue("")
 			case RenameSession:
 				cmd = renameSessionCmd(m)
 			case NewWindow:
-				cmd = newWindowCmd(m)
+				cmd = m.askDirectory(WindowDirectory, m.textInput.Value())
 			case RenameWindow:
 				cmd = renameWindowCmd(m)
-			case Filter:
-				m.filter = m.textInput.Value()
+			case NameLayout:
+				cmd = saveLayoutCmd(m, m.textInput.Value())
+			case SearchScrollback:
+				_, viewHeight := m.previewSize()
+				m.scrollback.NextMatch(true, viewHeight)
+			case SearchPanes:
+				query := m.textInput.Value()
+				pattern, err := compileSearch(query)
+				if err != nil {
+					m.pushError(err)
+				} else if pattern != nil {
+					m.search = &GlobalSearch{query: query, pattern: pattern, loading: true}
+					cmd = searchPanesCmd(m, query, pattern, m.options.SearchHistory)
+				}
+			case SendCommand:
+				cmd = sendCmd(m, m.sendTargets(), m.textInput.Value(), true)
+			case SendKeys:
+				cmd = sendCmd(m, m.sendTargets(), m.textInput.Value(), false)
+			}
+			if m.inputAction == action {
+				m.inputAction = None
+			}
+		default:
+			if m.pickingDirectory() {
+				m.updateDirectoryPicker(true)
 			}
-			m.inputAction = None
 		}
 	}
 
 	if m.inputAction == Filter {
-		m.sessions.filterText = m.textInput.Value()
-		m.windows.filterText = m.textInput.Value()
-		m.panes.filterText = m.textInput.Value()
+		m.setFilters(parseFilterQuery(m.textInput.Value(), m.filterFrame()))
+	}
+
+	if m.inputAction == SearchScrollback {
+		// Keeps the last valid pattern while the user is still typing
+		if pattern, err := compileSearch(m.textInput.Value()); err == nil {
+			m.scrollback.Search(pattern)
+		}
 	}
 
 	goto basic_handlers
@@ -272,54 +858,126 @@ input_mode:
 common_bindings:
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		switch m.options.Keys.Action(NormalMode, msg) {
+		case "quit":
 			cmd = tea.Quit
-		case "ctrl+p", "k", tea.KeyUp.String():
-			switch m.focusedFrame {
-			case 1:
-				m.sessions.SelectPrevious()
-			case 2:
-				m.windows.SelectPrevious()
-			case 3:
-				m.panes.SelectPrevious()
-			}
-			cmd = listEntitiesCmd
-		case "ctrl+n", "j", tea.KeyDown.String():
-			switch m.focusedFrame {
-			case 1:
-				m.sessions.SelectNext()
-			case 2:
-				m.windows.SelectNext()
-			case 3:
-				m.panes.SelectNext()
-			}
-			cmd = listEntitiesCmd
-		case "a":
+		case "up":
+			m.moveSelection(-1)
+			cmd = listEntitiesCmd(m)
+		case "down":
+			m.moveSelection(1)
+			cmd = listEntitiesCmd(m)
+		case "page-up":
+			m.moveSelection(-m.pageSize())
+			cmd = listEntitiesCmd(m)
+		case "page-down":
+			m.moveSelection(m.pageSize())
+			cmd = listEntitiesCmd(m)
+		case "half-page-up":
+			m.moveSelection(-max(1, m.pageSize()/2))
+			cmd = listEntitiesCmd(m)
+		case "half-page-down":
+			m.moveSelection(max(1, m.pageSize()/2))
+			cmd = listEntitiesCmd(m)
+		case "top":
+			m.moveSelection(-m.rowCount())
+			cmd = listEntitiesCmd(m)
+		case "bottom":
+			m.moveSelection(m.rowCount())
+			cmd = listEntitiesCmd(m)
+		case "show-all":
 			m.showAll = !m.showAll
-			cmd = listEntitiesCmd
+			cmd = listEntitiesCmd(m)
 		}
 	}
 
 basic_handlers:
 	switch msg := msg.(type) {
 	case tickMsg:
-		cmd = tea.Batch(tickCmd(), listEntitiesCmd)
+		cmd = tea.Batch(tickCmd(m.options.RefreshInterval), listEntitiesCmd(m))
+	case refreshMsg:
+		cmd = listEntitiesCmd(m)
+	case subscribedMsg:
+		m.subscription = msg.subscription
+		cmd = waitForEventCmd(m.subscription)
+	case controlEventMsg:
+		cmd = tea.Batch(waitForEventCmd(m.subscription), m.handleControlEvent(ControlEvent(msg)))
+	case controlClosedMsg:
+		// Lost the control mode connection, poll instead
+		m.subscription = nil
+		m.followedSession = -1
+		cmd = tea.Batch(tickCmd(m.options.RefreshInterval), listEntitiesCmd(m))
+	case previewRefreshMsg:
+		m.previewPending = false
+		if m.previewStale {
+			m.previewStale = false
+			cmd = m.refreshPreview()
+		}
 	case tea.WindowSizeMsg:
 		m.terminal.width = msg.Width
 		m.terminal.height = msg.Height
+		cmd = m.refreshPreview()
 	case listEntitiesMsg:
-		m.sessions.items = msg.sessions
-		m.windows.items = msg.windows
-		m.panes.items = msg.panes
-		if m.sessions.currentId == -1 && len(m.filter) == 0 {
-			m.sessions.currentId = msg.currentSession
-			m.windows.currentId = msg.currentWindow
-			m.panes.currentId = msg.currentPane
+		m.sessions.items = msg.Sessions
+		m.windows.items = msg.Windows
+		m.panes.items = msg.Panes
+		if m.sessions.currentId == -1 && !m.hasFilters() {
+			m.sessions.currentId = msg.CurrentSession
+			m.windows.currentId = msg.CurrentWindow
+			m.panes.currentId = msg.CurrentPane
+		}
+		cmd = m.refreshPreview()
+	case searchResultsMsg:
+		if m.search != nil && m.search.query == msg.query {
+			m.search.Load(msg)
+		}
+	case scrollbackMsg:
+		if msg.err != nil {
+			m.pushError(msg.err)
+		}
+		if m.scrollback != nil && m.scrollback.pane == msg.pane {
+			_, viewHeight := m.previewSize()
+			m.scrollback.Load(msg, viewHeight)
 		}
-		cmd = previewCmd(m)
 	case previewMsg:
 		m.preview.contents = string(msg)
+	case deletePlanMsg:
+		onConfirm := deleteCmd(m, msg.frame, msg.ids, msg.description)
+		if m.needsConfirmation(msg) {
+			m.confirmation = &Confirmation{
+				message:   fmt.Sprintf("Delete %s?", msg.description),
+				details:   msg.details,
+				frame:     msg.frame,
+				onConfirm: onConfirm,
+			}
+		} else {
+			m.list(msg.frame).ClearMarks()
+			cmd = onConfirm
+		}
+	case deletedMsg:
+		m.pushUndo(msg.undo)
+		if msg.err != nil {
+			m.pushError(msg.err)
+		} else {
+			m.pushInfo(fmt.Sprintf("Deleted %s. Undo: %s", msg.undo.description, m.options.Keys.Key(NormalMode, "undo")))
+		}
+		cmd = listEntitiesCmd(m)
+	case directoriesMsg:
+		if msg.err != nil {
+			m.pushError(msg.err)
+		}
+		m.directories = msg.entries
+		m.projects = msg.projects
+		if m.pickingDirectory() {
+			m.updateDirectoryPicker(false)
+		}
+	case infoMsg:
+		m.pushInfo(string(msg))
+	case errorMsg:
+		m.pushError(msg.err)
+		if m.inputAction == None {
+			m.textInput.SetValue("")
+		}
 	}
 
 	if m.showAll {
@@ -334,11 +992,53 @@ basic_handlers:
 	m.windows.Update()
 	m.panes.Update()
 
+	if m.treeView {
+		m.keepTreeCursor()
+	}
+
+	layout := m.gridLayout()
+	for frame := 1; frame <= 3; frame++ {
+		m.list(frame).Scroll(layout.list(frame).height - 2)
+	}
+
+	if session := m.previewSession(); m.subscription != nil && session != -1 && session != m.followedSession {
+		m.followedSession = session
+		cmd = tea.Batch(cmd, followCmd(m.subscription, session))
+	}
+
 	return m, cmd
 }
 
 func (m AppModel) View() string {
+	if m.showErrors {
+		return m.ErrorHistory().View(m.theme)
+	}
+
+	if m.showHelp {
+		return m.Help().View(m.theme)
+	}
+
 	preview := m.preview
+	if m.layoutMode {
+		preview = m.LayoutPreview()
+	}
+	if m.scrollback != nil {
+		width, height := m.previewSize()
+		preview = m.scrollback.RenderContents(m.theme, width, height)
+		m.sessions.frame.focused = false
+		m.windows.frame.focused = false
+		m.panes.frame.focused = false
+	}
+	if m.search != nil {
+		width, height := m.previewSize()
+		preview = m.search.RenderContents(m.theme, width, height)
+	}
+	if m.pickerAction != PickNothing || m.pickingDirectory() {
+		preview = m.picker.RenderContents(m.theme)
+	}
+	if m.confirmation != nil && len(m.confirmation.details) > 0 {
+		preview = m.confirmation.RenderContents(m.theme)
+	}
 
 	sessions := m.sessions.RenderContents(m.theme)
 	windows := m.windows.RenderContents(m.theme)
@@ -354,6 +1054,10 @@ func (m AppModel) View() string {
 		panes.title = "Panes"
 	}
 
+	sessions.title = filterTitle(sessions.title, m.sessions.filterText)
+	windows.title = filterTitle(windows.title, m.windows.filterText)
+	panes.title = filterTitle(panes.title, m.panes.filterText)
+
 	m.textInput.Width = m.terminal.width - 4
 	var status = Frame{
 		title:    "New name",
@@ -368,169 +1072,126 @@ func (m AppModel) View() string {
 		status = m.StatusBar()
 	case Filter:
 		status.title = "Filter"
+	case NameLayout:
+		status.title = "Layout name"
+	case SearchScrollback:
+		status.title = "Search (regular expression)"
+	case SearchPanes:
+		status.title = "Search all panes (regular expression)"
+	case SendCommand:
+		status.title = fmt.Sprintf("Command to run in %s", describePanes(m.sendTargets()))
+	case SendKeys:
+		status.title = fmt.Sprintf("Keys to send to %s, like C-c Up Enter", describePanes(m.sendTargets()))
+	case SessionDirectory, WindowDirectory:
+		status.title = fmt.Sprintf("Start directory, a path or part of one (complete: %s)", m.options.Keys.Key(InputMode, "complete"))
+	case OpenProject:
+		status.title = fmt.Sprintf("Project to open, a path or part of one (complete: %s)", m.options.Keys.Key(InputMode, "complete"))
 	}
 
-	return m.DrawGrid(preview, sessions, windows, panes, status)
+	if m.treeView {
+		return m.DrawGrid(preview, []Frame{m.TreeFrame()}, status)
+	}
+	return m.DrawGrid(preview, []Frame{sessions, windows, panes}, status)
 }
 
 func (m AppModel) StatusBar() Frame {
 	frame := Frame{title: "Status"}
 	normalStyle := lipgloss.NewStyle().Foreground(m.theme.Foreground).Background(m.theme.Background)
 	accentStyle := normalStyle.Foreground(m.theme.Accent)
-	left := []string{normalStyle.Render("Quit: q")}
-
-	if m.swapSrc == -1 {
-		left = append(left, normalStyle.Render("Go to: <enter>"))
-		left = append(left, normalStyle.Render("Delete: d"))
-		left = append(left, normalStyle.Render("Swap: s"))
-		if m.focusedFrame != 3 {
-			left = append(left, normalStyle.Render("New: n"))
-			left = append(left, normalStyle.Render("New (nameless): N"))
-			left = append(left, normalStyle.Render("Rename: r"))
-		} else {
-			left = append(left, normalStyle.Render("Vertical split: v"))
-			left = append(left, normalStyle.Render("Horizontal split: h"))
+	keys := m.options.Keys
+	mode, _ := m.keyMode()
+	left := []string{}
+
+	if len(m.notification) > 0 && m.notificationIsError {
+		frame.title = "Error"
+		left = []string{
+			normalStyle.Foreground(m.theme.Secondary).Render(m.notification),
+			normalStyle.Render("Dismiss: " + keys.Key(NormalMode, "dismiss")),
+			normalStyle.Render("History: " + keys.Key(NormalMode, "errors")),
 		}
-	} else {
-		left = append(left, accentStyle.Render("Swap: s/<space>/<enter>"))
-		left = append(left, normalStyle.Render("Cancel: <esc>"))
+		goto render
+	} else if len(m.notification) > 0 {
+		frame.title = "Info"
+		left = []string{accentStyle.Render(m.notification), normalStyle.Render("Dismiss: " + keys.Key(NormalMode, "dismiss"))}
+		goto render
 	}
 
-	if m.showAll {
-		left = append(left, accentStyle.Render("Show all: a"))
-	} else {
-		left = append(left, normalStyle.Render("Show all: a"))
+	if m.confirmation != nil {
+		frame.title = "Confirm"
+		choices := fmt.Sprintf(" (%s/%s)", keys.Key(ConfirmMode, "yes"), keys.Key(ConfirmMode, "no"))
+		left = []string{accentStyle.Render(m.confirmation.message + choices)}
+		goto render
 	}
 
-	if m.swapSrc == -1 {
-		if len(m.textInput.Value()) > 0 {
-			left = append(left, accentStyle.Render("Filter: /"))
-		} else {
-			left = append(left, normalStyle.Render("Filter: /"))
-		}
+	left = m.hints(normalStyle, accentStyle)
+	if len(left) == 0 {
+		left = []string{""}
 	}
 
+render:
 	rightString := normalStyle.Foreground(m.theme.Secondary).Render(strings.TrimSpace(Version))
 
+	// The help has whatever did not fit, so it always gets a place
 	separator := normalStyle.Render(" | ")
+	helpString := ""
+	if key := keys.Key(mode, "help"); len(key) > 0 {
+		helpString = separator + normalStyle.Render("Help: "+key)
+	}
+
 	maxWidth := uint(m.terminal.width - 7 - lipgloss.Width(rightString))
-	leftString := left[0]
+	leftWidth := max(0, int(maxWidth)-lipgloss.Width(helpString))
+	leftString := truncate.StringWithTail(left[0], uint(leftWidth), "…")
 	for i, v := range left {
 		if i == 0 {
 			continue
 		}
 		newWidth := lipgloss.Width(leftString) + 3 + lipgloss.Width(v)
-		if newWidth <= int(maxWidth) {
+		if newWidth <= leftWidth {
 			leftString = fmt.Sprintf("%s%s%s", leftString, separator, v)
 		}
 	}
-	leftString = normalStyle.Width(int(maxWidth)).Render(leftString)
+	leftString = normalStyle.Width(int(maxWidth)).Render(leftString + helpString)
 
 	frame.contents = leftString + normalStyle.Render(" ") + rightString
 	return frame
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second*1, func(t time.Time) tea.Msg {
+func tickCmd(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
-func listEntitiesCmd() tea.Msg {
-	// Fetches info about all sessions, windows and panes at once
-	c := exec.Command("tmux",
-		"list-panes", "-aF", "#{session_id}\t#{window_id}\t#{pane_id}\t#{session_name}\t#{window_name}\t#{pane_current_command}", ";",
-		"display-message", "-p", "#{session_id}\t#{window_id}\t#{pane_id}")
-	bytes, err := c.Output()
-	if err != nil {
-		return nil
-	}
-
-	sessions := []TmuxEntity{}
-	windows := []TmuxEntity{}
-	panes := []TmuxEntity{}
-
-	currentSession := 0
-	currentWindow := 0
-	currentPane := 0
-
-	scanner := bufio.NewScanner(strings.NewReader(string(bytes[:])))
-	for scanner.Scan() {
-		parts := strings.Split(scanner.Text(), "\t")
-
-		session_id, err := strconv.Atoi(strings.Replace(parts[0], "$", "", 1))
-		if err != nil {
-			continue
-		}
-
-		window_id, err := strconv.Atoi(strings.Replace(parts[1], "@", "", 1))
-		if err != nil {
-			continue
-		}
-
-		pane_id, err := strconv.Atoi(strings.Replace(parts[2], "%", "", 1))
+func listEntitiesCmd(m AppModel) tea.Cmd {
+	return func() tea.Msg {
+		entities, err := m.client.ListEntities()
 		if err != nil {
-			continue
+			return errorMsg{err}
 		}
 
-		if len(parts) == 3 {
-			currentSession = session_id
-			currentWindow = window_id
-			currentPane = pane_id
-			continue
+		if len(entities.Sessions) == 0 {
+			return errorMsg{errors.New("No sessions found. Is tmux running?")}
 		}
 
-		session_name := parts[3]
-		window_name := parts[4]
-		pane_name := parts[5]
-
-		sessions = append(sessions, TmuxEntity{session_id, session_name, -1})
-		windows = append(windows, TmuxEntity{window_id, window_name, session_id})
-		panes = append(panes, TmuxEntity{pane_id, pane_name, window_id})
-	}
-
-	if len(sessions) == 0 {
-		return errorMsg("No sessions found. Is tmux running?")
-	}
+		entities.linkPaths()
+		entities.sortWindows()
 
-	eq := func(a, b TmuxEntity) bool {
-		return a.id == b.id
+		return listEntitiesMsg(entities)
 	}
-
-	sessions = slices.CompactFunc(sessions, eq)
-	windows = slices.CompactFunc(windows, eq)
-	panes = slices.CompactFunc(panes, eq)
-
-	msg := listEntitiesMsg{}
-
-	msg.sessions = sessions
-	msg.windows = windows
-	msg.panes = panes
-
-	msg.currentSession = currentSession
-	msg.currentWindow = currentWindow
-	msg.currentPane = currentPane
-
-	return msg
 }
 
 func previewCmd(m AppModel) tea.Cmd {
 	return func() tea.Msg {
-		id := ""
 		switch m.focusedFrame {
 		case 1:
-			id = fmt.Sprintf("$%d", m.sessions.currentId)
+			return previewMsg(m.sessionPreview(m.sessions.currentId))
 		case 2:
-			id = fmt.Sprintf("@%d", m.windows.currentId)
-		case 3:
-			id = fmt.Sprintf("%%%d", m.panes.currentId)
+			return previewMsg(m.windowPreview(m.windows.currentId))
 		}
-		c := exec.Command("tmux", "capture-pane", "-ep", "-t", id)
-		bytes, err := c.Output()
+		preview, err := m.client.CapturePane(paneTarget(m.panes.currentId), CaptureOptions{Escapes: true})
 		if err != nil {
 			return nil
 		}
-		preview := string(bytes[:])
 		return previewMsg(preview)
 	}
 }
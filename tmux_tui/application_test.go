@@ -0,0 +1,418 @@
+package tmux_tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel creates a model on top of client and feeds it what Init
+// loads, with the sessions list focused.
+func newTestModel(t *testing.T, client *FakeClient) AppModel {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	options := DefaultOptions()
+	options.ConfirmPolicy = ConfirmNever
+	options.RefreshInterval = time.Hour
+	m := NewAppModel(DraculaTheme, client, options)
+	m = send(t, m, tea.WindowSizeMsg{Width: 200, Height: 50})
+	return run(t, m, m.Init())
+}
+
+// send feeds msg to the model and runs the commands it returns until they
+// settle.
+func send(t *testing.T, m AppModel, msg tea.Msg) AppModel {
+	t.Helper()
+	model, cmd := m.Update(msg)
+	return run(t, model.(AppModel), cmd)
+}
+
+// run runs cmd and feeds what it returns back to the model. Commands that do
+// not return right away, like ticks, are dropped.
+func run(t *testing.T, m AppModel, cmd tea.Cmd) AppModel {
+	t.Helper()
+	if cmd == nil {
+		return m
+	}
+
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(100 * time.Millisecond):
+		return m
+	}
+
+	// Batches and sequences are both lists of commands
+	if value := reflect.ValueOf(msg); value.Kind() == reflect.Slice && value.Type().Elem() == reflect.TypeOf(cmd) {
+		for i := range value.Len() {
+			m = run(t, m, value.Index(i).Interface().(tea.Cmd))
+		}
+		return m
+	}
+	if msg == nil {
+		return m
+	}
+	if _, ok := msg.(tea.QuitMsg); ok {
+		return m
+	}
+	return send(t, m, msg)
+}
+
+// press types keys one by one, with "space", "enter", "esc" and "ctrl+u"
+// standing for those keys.
+func press(t *testing.T, m AppModel, keys ...string) AppModel {
+	t.Helper()
+	for _, key := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		switch key {
+		case "space":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "ctrl+u":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlU}
+		}
+		m = send(t, m, msg)
+	}
+	return m
+}
+
+func windowIds(client *FakeClient, session int) []int {
+	ids := []int{}
+	for _, window := range client.Entities.Windows {
+		if window.parent == session {
+			ids = append(ids, window.id)
+		}
+	}
+	return ids
+}
+
+func TestListsEntities(t *testing.T) {
+	client := NewFakeClient()
+	work, window, pane := client.AddSession("work")
+	client.AddSession("play")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, window, pane
+
+	m := newTestModel(t, client)
+
+	if len(m.sessions.items) != 2 || len(m.windows.items) != 2 || len(m.panes.items) != 2 {
+		t.Fatalf("listed %d sessions, %d windows and %d panes, expected 2 of each", len(m.sessions.items), len(m.windows.items), len(m.panes.items))
+	}
+	if m.sessions.currentId != work || m.windows.currentId != window || m.panes.currentId != pane {
+		t.Errorf("selected %d, %d and %d, expected the current %d, %d and %d", m.sessions.currentId, m.windows.currentId, m.panes.currentId, work, window, pane)
+	}
+}
+
+func TestGoToSession(t *testing.T) {
+	client := NewFakeClient()
+	work, window, pane := client.AddSession("work")
+	client.AddSession("play")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, window, pane
+
+	m := newTestModel(t, client)
+	m = press(t, m, "j")
+	session := m.sessions.currentId
+	press(t, m, "enter")
+
+	if client.Entities.CurrentSession != session || !slices.Contains(windowIds(client, session), client.Entities.CurrentWindow) {
+		t.Errorf("went to window %d of session %d, expected a window of session %d", client.Entities.CurrentWindow, client.Entities.CurrentSession, session)
+	}
+}
+
+func TestGoToWindow(t *testing.T) {
+	client := NewFakeClient()
+	work, window, pane := client.AddSession("work")
+	second, secondPane := client.AddWindow(work, "second")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, window, pane
+
+	m := newTestModel(t, client)
+	press(t, m, "2", "j", "enter")
+
+	if client.Entities.CurrentWindow != second || client.Entities.CurrentPane != secondPane {
+		t.Errorf("went to window %d and pane %d, expected %d and %d", client.Entities.CurrentWindow, client.Entities.CurrentPane, second, secondPane)
+	}
+}
+
+func TestRenameSession(t *testing.T) {
+	client := NewFakeClient()
+	work, window, pane := client.AddSession("work")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, window, pane
+
+	m := newTestModel(t, client)
+	press(t, m, "r", "ctrl+u", "n", "e", "w", "enter")
+
+	if name := client.Entities.Sessions[0].name; name != "new" {
+		t.Errorf("session is named %q, expected %q", name, "new")
+	}
+}
+
+func TestDeleteWindow(t *testing.T) {
+	client := NewFakeClient()
+	work, first, pane := client.AddSession("work")
+	second, _ := client.AddWindow(work, "second")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, first, pane
+
+	m := newTestModel(t, client)
+	m = press(t, m, "2", "d")
+
+	if ids := windowIds(client, work); !slices.Equal(ids, []int{second}) {
+		t.Errorf("windows left are %v, expected %v", ids, []int{second})
+	}
+	if len(m.windows.items) != 1 {
+		t.Errorf("listed %d windows after deleting one of two", len(m.windows.items))
+	}
+}
+
+func TestFakeListEntitiesCopies(t *testing.T) {
+	client := NewFakeClient()
+	client.AddSession("work")
+
+	entities, _ := client.ListEntities()
+	entities.Windows[0].window.Index = 5
+	entities.Panes[0].pane.CurrentPath = "/tmp"
+
+	if client.Entities.Windows[0].window.Index != 0 || client.Entities.Panes[0].pane.CurrentPath != "" {
+		t.Error("changing the listed entities changed the fake's")
+	}
+}
+
+func TestDeleteMarkedWindows(t *testing.T) {
+	client := NewFakeClient()
+	work, first, pane := client.AddSession("work")
+	second, _ := client.AddWindow(work, "second")
+	client.AddWindow(work, "third")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, first, pane
+
+	m := newTestModel(t, client)
+	m = press(t, m, "2", "space", "j", "space", "d")
+
+	if ids := windowIds(client, work); !slices.Equal(ids, []int{second}) {
+		t.Errorf("windows left are %v, expected %v", ids, []int{second})
+	}
+	if len(m.errors) > 0 {
+		t.Errorf("unexpected errors %v", m.errors)
+	}
+}
+
+func TestMoveMarkedWindows(t *testing.T) {
+	client := NewFakeClient()
+	work, first, pane := client.AddSession("work")
+	second, _ := client.AddWindow(work, "second")
+	client.AddWindow(work, "third")
+	dest, _, _ := client.AddSession("dest")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, first, pane
+
+	m := newTestModel(t, client)
+	m = press(t, m, "2", "space", "space", "m")
+	if len(m.moveSrcs) != 2 {
+		t.Fatalf("moving %v, expected two windows", m.moveSrcs)
+	}
+	press(t, m, "j", "m")
+
+	if ids := windowIds(client, dest); len(ids) != 3 || !slices.Contains(ids, first) || !slices.Contains(ids, second) {
+		t.Errorf("windows of dest are %v, expected %d and %d to be moved there", ids, first, second)
+	}
+}
+
+func TestNewWindowWhenRankingFails(t *testing.T) {
+	client := NewFakeClient()
+	work, window, pane := client.AddSession("work")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, window, pane
+	m := newTestModel(t, client)
+
+	// The data directory cannot be created under a file
+	data := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(data, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_DATA_HOME", data)
+
+	msg := newWindowCmd(m, "new", t.TempDir())()
+
+	if _, ok := msg.(errorMsg); !ok {
+		t.Errorf("returned %#v, expected the ranking error", msg)
+	}
+	if ids := windowIds(client, work); len(ids) != 2 {
+		t.Errorf("windows of work are %v, expected the new one too", ids)
+	}
+}
+
+func TestWheelStaysInList(t *testing.T) {
+	client := NewFakeClient()
+	work, first, pane := client.AddSession("work")
+	last, _ := client.AddWindow(work, "last")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, first, pane
+	m := newTestModel(t, client)
+
+	for range 3 {
+		m = run(t, m, m.scroll(2, true))
+	}
+	if m.windows.currentId != last {
+		t.Errorf("selected %d after scrolling down, expected the last window %d", m.windows.currentId, last)
+	}
+	for range 3 {
+		m = run(t, m, m.scroll(2, false))
+	}
+	if m.windows.currentId != first {
+		t.Errorf("selected %d after scrolling up, expected the first window %d", m.windows.currentId, first)
+	}
+}
+
+func TestPaneActionsWithoutPanes(t *testing.T) {
+	m := newTestModel(t, NewFakeClient())
+
+	if msg := goToPaneCmd(m)(); msg != nil {
+		t.Errorf("going to no pane returned %#v", msg)
+	}
+	if msg := breakPaneCmd(m)(); msg != nil {
+		t.Errorf("breaking no pane out returned %#v", msg)
+	}
+}
+
+func TestActionsWithNothingSelected(t *testing.T) {
+	client := NewFakeClient()
+	m := newTestModel(t, client)
+
+	for _, frame := range []string{"1", "2"} {
+		m = press(t, m, frame, "enter", "r")
+		if m.inputAction != None {
+			t.Errorf("renaming in frame %s with nothing selected", frame)
+		}
+	}
+	if slices.ContainsFunc(client.Calls, func(call string) bool { return call != "Subscribe" && call != "ListEntities" }) {
+		t.Errorf("called %v with nothing selected", client.Calls)
+	}
+}
+
+func TestUndoFailedDelete(t *testing.T) {
+	client := NewFakeClient()
+	work, first, pane := client.AddSession("work")
+	second, _ := client.AddWindow(work, "second")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, first, pane
+	client.Failures = map[string]error{"KillWindow " + windowTarget(first): errors.New("can't kill")}
+
+	m := newTestModel(t, client)
+	m = press(t, m, "2", "d")
+	if len(m.undo) > 0 {
+		t.Errorf("kept %d undo entries for a delete that failed", len(m.undo))
+	}
+	press(t, m, "u")
+
+	if ids := windowIds(client, work); !slices.Equal(ids, []int{first, second}) {
+		t.Errorf("windows are %v after undoing, expected %v", ids, []int{first, second})
+	}
+}
+
+func TestUndoPartlyFailedDelete(t *testing.T) {
+	client := NewFakeClient()
+	work, first, pane := client.AddSession("work")
+	second, _ := client.AddWindow(work, "second")
+	client.AddWindow(work, "third")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, first, pane
+	client.Failures = map[string]error{"KillWindow " + windowTarget(first): errors.New("can't kill")}
+
+	m := newTestModel(t, client)
+	m = press(t, m, "2", "space", "space", "d")
+	press(t, m, "u")
+
+	ids := windowIds(client, work)
+	if len(ids) != 3 || !slices.Contains(ids, first) || slices.Contains(ids, second) {
+		t.Errorf("windows are %v after undoing, expected %d, the third one and %d recreated", ids, first, second)
+	}
+	created := slices.DeleteFunc(slices.Clone(client.Calls), func(call string) bool { return !strings.HasPrefix(call, "NewWindow") })
+	if len(created) != 1 {
+		t.Errorf("undoing created windows with %v, expected only the deleted one", created)
+	}
+}
+
+func TestScrollbackLoadFails(t *testing.T) {
+	client := NewFakeClient()
+	work, window, pane := client.AddSession("work")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, window, pane
+	client.Contents = map[int]string{pane: "prompt\n"}
+	firstPage := "CapturePane " + paneTarget(pane) + " " + fmt.Sprint(-scrollbackPage)
+	client.Failures = map[string]error{firstPage: errors.New("can't capture")}
+
+	m := newTestModel(t, client)
+	m = press(t, m, "3", "0")
+	if m.scrollback == nil || m.scrollback.loading || len(m.errors) == 0 {
+		t.Fatalf("still loading or without an error after the scrollback failed to load")
+	}
+
+	client.Failures = nil
+	m = press(t, m, "k")
+	if !slices.Equal(m.scrollback.lines, []string{"prompt"}) {
+		t.Errorf("scrollback has %v after trying again, expected the pane contents", m.scrollback.lines)
+	}
+}
+
+func TestPreviewCapturesVisiblePanes(t *testing.T) {
+	client := NewFakeClient()
+	work, window, pane := client.AddSession("work")
+	hidden := client.AddPane(window, "zsh")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, window, pane
+	client.Entities.Windows[0].window.Layout = "c195,80x24,0,0[80x12,0,0,0,80x11,0,13,1]"
+	client.Entities.Windows[0].window.Zoomed = true
+	client.Entities.Panes[0].pane.Active = true
+	client.Entities.Panes[1].pane.Top = 13
+
+	m := newTestModel(t, client)
+	client.Calls = nil
+	m.windowPreview(window)
+
+	if !slices.Equal(client.Calls, []string{"CapturePane " + paneTarget(pane)}) {
+		t.Errorf("captured with %v, expected only the zoomed pane and not %d", client.Calls, hidden)
+	}
+}
+
+func TestPreviewCoalesced(t *testing.T) {
+	m := newTestModel(t, NewFakeClient())
+	m.previewPending = false
+
+	if m.refreshPreview() == nil {
+		t.Fatal("did not capture the preview")
+	}
+	if m.refreshPreview() != nil || m.refreshPreview() != nil {
+		t.Fatal("captured the preview again right away")
+	}
+	model, cmd := m.Update(previewRefreshMsg{})
+	m = model.(AppModel)
+	if cmd == nil || !m.previewPending || m.previewStale {
+		t.Error("did not capture the preview once more after waiting")
+	}
+}
+
+func TestSendPastFailures(t *testing.T) {
+	client := NewFakeClient()
+	work, window, first := client.AddSession("work")
+	second := client.AddPane(window, "zsh")
+	third := client.AddPane(window, "zsh")
+	client.Entities.CurrentSession, client.Entities.CurrentWindow, client.Entities.CurrentPane = work, window, first
+	client.Failures = map[string]error{"SendKeys " + paneTarget(second) + " false C-c": errors.New("can't send")}
+	m := newTestModel(t, client)
+
+	msg, ok := sendCmd(m, []int{first, second, third}, "C-c", false)().(errorMsg)
+	if !ok {
+		t.Fatalf("returned %#v, expected an error", msg)
+	}
+	if !slices.Contains(client.Calls, "SendKeys "+paneTarget(third)+" false C-c") {
+		t.Errorf("stopped sending at the failing pane: %v", client.Calls)
+	}
+	sent := fmt.Sprintf("sent to %s, %s", paneTarget(first), paneTarget(third))
+	if text := msg.err.Error(); !strings.Contains(text, sent) || !strings.Contains(text, paneTarget(second)+": can't send") {
+		t.Errorf("reported %q, expected where it was sent and where it failed", text)
+	}
+}
@@ -0,0 +1,149 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Arrangement is how the preview and the lists share the screen.
+type Arrangement string
+
+const (
+	// ArrangeAuto picks one of the others from the size of the terminal.
+	ArrangeAuto          Arrangement = "auto"
+	ArrangePreviewTop    Arrangement = "preview-top"
+	ArrangePreviewRight  Arrangement = "preview-right"
+	ArrangePreviewHidden Arrangement = "preview-hidden"
+	ArrangeStacked       Arrangement = "stacked"
+	ArrangeZoomed        Arrangement = "zoomed"
+)
+
+// Arrangements in the order the arrange action cycles through them.
+var Arrangements = []Arrangement{ArrangeAuto, ArrangePreviewTop, ArrangePreviewRight, ArrangePreviewHidden, ArrangeStacked, ArrangeZoomed}
+
+func ParseArrangement(arrangement string) (Arrangement, error) {
+	if slices.Contains(Arrangements, Arrangement(arrangement)) {
+		return Arrangement(arrangement), nil
+	}
+	return ArrangeAuto, fmt.Errorf("unknown arrangement %q, expected auto, preview-top, preview-right, preview-hidden, stacked or zoomed", arrangement)
+}
+
+// autoArrangement is the arrangement that fits a terminal of width by height:
+// the preview goes to the right or away when there is little height, and the
+// lists are stacked when there is little width. Tiny terminals only show
+// one frame.
+func autoArrangement(width int, height int) Arrangement {
+	switch {
+	case width < 40 || height < 15, width < 90 && height < 24:
+		return ArrangeZoomed
+	case width < 90:
+		return ArrangeStacked
+	case height < 30 && width >= 150:
+		return ArrangePreviewRight
+	case height < 30:
+		return ArrangePreviewHidden
+	}
+	return ArrangePreviewTop
+}
+
+// nextArrangement is the arrangement after the current one.
+func (m AppModel) nextArrangement() Arrangement {
+	i := slices.Index(Arrangements, m.arrangement)
+	return Arrangements[(i+1)%len(Arrangements)]
+}
+
+// describeArrangement names the arrangement in use, along with the one auto
+// picked.
+func (m AppModel) describeArrangement() string {
+	if m.arrangement == ArrangeAuto {
+		return fmt.Sprintf("%s (%s)", ArrangeAuto, autoArrangement(m.terminal.width, m.terminal.height))
+	}
+	return string(m.arrangement)
+}
+
+// previewNeeded tells whether the preview has something that cannot be
+// hidden: the scrollback, search results, a picker, a layout or what a
+// confirmation is about.
+func (m AppModel) previewNeeded() bool {
+	return m.layoutMode || m.scrollback != nil || m.search != nil || m.pickerAction != PickNothing || m.pickingDirectory() ||
+		(m.confirmation != nil && len(m.confirmation.details) > 0)
+}
+
+// shownFrames are the lists that are drawn, which is only the tree in tree
+// view.
+func (m AppModel) shownFrames() []int {
+	if m.treeView {
+		return []int{treeFrame}
+	}
+	return []int{1, 2, 3}
+}
+
+// split divides total in n parts, the last one taking what is left.
+func split(total int, n int) []int {
+	parts := make([]int, n)
+	for i := range parts {
+		parts[i] = total / n
+	}
+	parts[n-1] = total - (n-1)*(total/n)
+	return parts
+}
+
+func (m AppModel) gridLayout() gridLayout {
+	w := m.terminal.width
+	h := m.terminal.height
+	body := h - 3 // Makes room for the status bar
+	frames := m.shownFrames()
+
+	arrangement := m.arrangement
+	if arrangement == ArrangeAuto {
+		arrangement = autoArrangement(w, h)
+	}
+	if m.previewNeeded() && (arrangement == ArrangePreviewHidden || arrangement == ArrangeStacked) {
+		arrangement = ArrangeZoomed
+	}
+
+	layout := gridLayout{arrangement: arrangement, status: rect{0, body, w, 3}}
+	columns := func(y int, height int) {
+		x := 0
+		for i, width := range split(w, len(frames)) {
+			layout.lists = append(layout.lists, rect{x, y, width, height})
+			layout.frames = append(layout.frames, frames[i])
+			x += width
+		}
+	}
+	rows := func(width int) {
+		y := 0
+		for i, height := range split(body, len(frames)) {
+			layout.lists = append(layout.lists, rect{0, y, width, height})
+			layout.frames = append(layout.frames, frames[i])
+			y += height
+		}
+	}
+
+	switch arrangement {
+	case ArrangePreviewTop:
+		top := h*m.options.PreviewHeight/100 - 3
+		layout.preview = rect{0, 0, w, top}
+		columns(top, body-top)
+	case ArrangePreviewRight:
+		left := w - w*m.options.PreviewWidth/100
+		layout.preview = rect{left, 0, w - left, body}
+		rows(left)
+	case ArrangePreviewHidden:
+		columns(0, body)
+	case ArrangeStacked:
+		rows(w)
+	case ArrangeZoomed:
+		switch {
+		case m.previewNeeded():
+			layout.preview = rect{0, 0, w, body}
+		case m.treeView:
+			layout.lists = []rect{{0, 0, w, body}}
+			layout.frames = []int{treeFrame}
+		default:
+			layout.lists = []rect{{0, 0, w, body}}
+			layout.frames = []int{m.focusedFrame}
+		}
+	}
+	return layout
+}
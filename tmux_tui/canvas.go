@@ -0,0 +1,144 @@
+package tmux_tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Connections of a cell of a canvas to its neighbours.
+const (
+	lineUp = 1 << iota
+	lineDown
+	lineLeft
+	lineRight
+)
+
+var lineRunes = map[int]rune{
+	lineUp: '│', lineDown: '│', lineUp | lineDown: '│',
+	lineLeft: '─', lineRight: '─', lineLeft | lineRight: '─',
+	lineDown | lineRight: '┌', lineDown | lineLeft: '┐',
+	lineUp | lineRight: '└', lineUp | lineLeft: '┘',
+	lineUp | lineDown | lineRight: '├', lineUp | lineDown | lineLeft: '┤',
+	lineLeft | lineRight | lineDown: '┬', lineLeft | lineRight | lineUp: '┴',
+	lineUp | lineDown | lineLeft | lineRight: '┼',
+}
+
+// canvas is a grid of cells where boxes and text are drawn. Boxes that share
+// an edge are joined with the right line characters.
+type canvas struct {
+	width       int
+	height      int
+	lines       [][]int
+	text        [][]rune
+	highlighted [][]bool
+}
+
+func newCanvas(width int, height int) *canvas {
+	c := &canvas{width: width, height: height}
+	c.lines = make([][]int, height)
+	c.text = make([][]rune, height)
+	c.highlighted = make([][]bool, height)
+	for i := range height {
+		c.lines[i] = make([]int, width)
+		c.text[i] = make([]rune, width)
+		c.highlighted[i] = make([]bool, width)
+	}
+	return c
+}
+
+func (c *canvas) inside(row int, col int) bool {
+	return row >= 0 && row < c.height && col >= 0 && col < c.width
+}
+
+// box draws the edges of a rectangle, corners included.
+func (c *canvas) box(left int, top int, right int, bottom int, highlight bool) {
+	for col := left; col <= right; col++ {
+		for _, row := range []int{top, bottom} {
+			if !c.inside(row, col) {
+				continue
+			}
+			if col > left {
+				c.lines[row][col] |= lineLeft
+			}
+			if col < right {
+				c.lines[row][col] |= lineRight
+			}
+			c.highlighted[row][col] = c.highlighted[row][col] || highlight
+		}
+	}
+	for row := top; row <= bottom; row++ {
+		for _, col := range []int{left, right} {
+			if !c.inside(row, col) {
+				continue
+			}
+			if row > top {
+				c.lines[row][col] |= lineUp
+			}
+			if row < bottom {
+				c.lines[row][col] |= lineDown
+			}
+			c.highlighted[row][col] = c.highlighted[row][col] || highlight
+		}
+	}
+}
+
+// write puts text at row starting at col, cut before column end.
+func (c *canvas) write(row int, col int, end int, text string, highlight bool) {
+	for _, r := range text {
+		if col >= end {
+			break
+		}
+		if c.inside(row, col) {
+			c.text[row][col] = r
+			c.highlighted[row][col] = highlight
+		}
+		col++
+	}
+}
+
+// writeBlock puts the lines of text in the rectangle that starts at row and
+// col, cutting what does not fit.
+func (c *canvas) writeBlock(row int, col int, bottom int, end int, text string) {
+	for i, line := range strings.Split(text, "\n") {
+		if row+i >= bottom {
+			break
+		}
+		c.write(row+i, col, end, line, false)
+	}
+}
+
+// render draws the canvas, using highlight for the highlighted cells. Runs of
+// cells with the same style are rendered together.
+func (c *canvas) render(normal lipgloss.Style, highlight lipgloss.Style) string {
+	rows := []string{}
+	for row := range c.height {
+		var b strings.Builder
+		run := []rune{}
+		runHighlighted := false
+		for col := range c.width {
+			r := ' '
+			if c.text[row][col] != 0 {
+				r = c.text[row][col]
+			} else if c.lines[row][col] != 0 {
+				r = lineRunes[c.lines[row][col]]
+			}
+			if c.highlighted[row][col] != runHighlighted && len(run) > 0 {
+				b.WriteString(c.style(runHighlighted, normal, highlight).Render(string(run)))
+				run = run[:0]
+			}
+			runHighlighted = c.highlighted[row][col]
+			run = append(run, r)
+		}
+		b.WriteString(c.style(runHighlighted, normal, highlight).Render(string(run)))
+		rows = append(rows, b.String())
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (c *canvas) style(highlighted bool, normal lipgloss.Style, highlight lipgloss.Style) lipgloss.Style {
+	if highlighted {
+		return highlight
+	}
+	return normal
+}
@@ -0,0 +1,160 @@
+package tmux_tui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is what can be set in the configuration file. Fields left out keep
+// their defaults, and command line options take precedence.
+type Config struct {
+	// Theme is the handle of a builtin theme or the path to a theme file.
+	Theme string `yaml:"theme"`
+	// RefreshInterval is how often the lists are refreshed when tmux's
+	// control mode is not available, like "1s" or "500ms".
+	RefreshInterval string `yaml:"refresh_interval"`
+	// StartupFrame is the list focused at startup: sessions, windows or
+	// panes.
+	StartupFrame string `yaml:"startup_frame"`
+	ShowAll      bool   `yaml:"show_all"`
+	// View is either lists (the default) or tree.
+	View string `yaml:"view"`
+	// Arrangement is auto, preview-top, preview-right, preview-hidden,
+	// stacked or zoomed.
+	Arrangement string `yaml:"arrangement"`
+	// PreviewHeight and PreviewWidth are percentages of the screen.
+	PreviewHeight int `yaml:"preview_height"`
+	PreviewWidth  int `yaml:"preview_width"`
+	// Confirm is the confirmation policy: busy, always or never.
+	Confirm       string `yaml:"confirm"`
+	SearchHistory *int   `yaml:"search_history"`
+	// ProjectRoots are where git repositories are looked for, ProjectDepth
+	// levels down, to offer them as start directories.
+	ProjectRoots []string `yaml:"project_roots"`
+	ProjectDepth int      `yaml:"project_depth"`
+	// Keys binds actions to keys, by mode and action.
+	Keys map[string]map[string][]string `yaml:"keys"`
+}
+
+func ConfigPath() string {
+	return filepath.Join(ConfigDir(), "config.yaml")
+}
+
+// LoadConfig reads the configuration file at path. A missing file is an
+// empty configuration.
+func LoadConfig(path string) (Config, error) {
+	config := Config{}
+
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	if _, err := config.Options(); err != nil {
+		return config, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Options are the options set by the configuration, on top of the defaults.
+func (config Config) Options() (Options, error) {
+	options := DefaultOptions()
+
+	if len(config.RefreshInterval) > 0 {
+		interval, err := time.ParseDuration(config.RefreshInterval)
+		if err != nil {
+			return options, fmt.Errorf("refresh_interval: %w", err)
+		}
+		if interval <= 0 {
+			return options, fmt.Errorf("refresh_interval must be positive")
+		}
+		options.RefreshInterval = interval
+	}
+
+	switch config.StartupFrame {
+	case "":
+	case "sessions":
+		options.StartupFrame = 1
+	case "windows":
+		options.StartupFrame = 2
+	case "panes":
+		options.StartupFrame = 3
+	default:
+		return options, fmt.Errorf("startup_frame must be sessions, windows or panes, not %q", config.StartupFrame)
+	}
+
+	options.ShowAll = config.ShowAll
+
+	switch config.View {
+	case "", "lists":
+	case "tree":
+		options.TreeView = true
+	default:
+		return options, fmt.Errorf("view must be lists or tree, not %q", config.View)
+	}
+
+	if len(config.Arrangement) > 0 {
+		arrangement, err := ParseArrangement(config.Arrangement)
+		if err != nil {
+			return options, fmt.Errorf("arrangement: %w", err)
+		}
+		options.Arrangement = arrangement
+	}
+
+	if config.PreviewHeight != 0 {
+		if config.PreviewHeight < 10 || config.PreviewHeight > 90 {
+			return options, fmt.Errorf("preview_height must be between 10 and 90, not %d", config.PreviewHeight)
+		}
+		options.PreviewHeight = config.PreviewHeight
+	}
+	if config.PreviewWidth != 0 {
+		if config.PreviewWidth < 10 || config.PreviewWidth > 90 {
+			return options, fmt.Errorf("preview_width must be between 10 and 90, not %d", config.PreviewWidth)
+		}
+		options.PreviewWidth = config.PreviewWidth
+	}
+
+	if len(config.Confirm) > 0 {
+		policy, err := ParseConfirmPolicy(config.Confirm)
+		if err != nil {
+			return options, fmt.Errorf("confirm: %w", err)
+		}
+		options.ConfirmPolicy = policy
+	}
+
+	if config.SearchHistory != nil {
+		options.SearchHistory = *config.SearchHistory
+	}
+
+	options.ProjectRoots = config.ProjectRoots
+	if config.ProjectDepth < 0 {
+		return options, fmt.Errorf("project_depth must not be negative, not %d", config.ProjectDepth)
+	}
+	if config.ProjectDepth > 0 {
+		options.ProjectDepth = config.ProjectDepth
+	}
+
+	keys, err := DefaultKeyMap.Merge(config.Keys)
+	if err != nil {
+		return options, err
+	}
+	options.Keys = keys
+
+	return options, nil
+}
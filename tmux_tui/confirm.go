@@ -0,0 +1,219 @@
+package tmux_tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// ConfirmBusy asks before deleting anything that runs more than an idle
+	// shell, and before deleting several things at once.
+	ConfirmBusy   ConfirmPolicy = "busy"
+	ConfirmAlways ConfirmPolicy = "always"
+	ConfirmNever  ConfirmPolicy = "never"
+)
+
+type (
+	// ConfirmPolicy decides when deleting asks for confirmation.
+	ConfirmPolicy string
+
+	// Confirmation is a yes/no question asked before running a command.
+	// details are shown in place of the preview.
+	Confirmation struct {
+		message   string
+		details   []string
+		frame     int
+		onConfirm tea.Cmd
+	}
+
+	// deletePlanMsg describes what deleting ids of frame would kill.
+	deletePlanMsg struct {
+		frame       int
+		ids         []int
+		description string
+		details     []string
+		busy        bool
+	}
+
+	// deletedMsg carries what is needed to undo a delete.
+	deletedMsg struct {
+		undo undoEntry
+		err  error
+	}
+)
+
+func ParseConfirmPolicy(policy string) (ConfirmPolicy, error) {
+	switch ConfirmPolicy(policy) {
+	case ConfirmBusy, ConfirmAlways, ConfirmNever:
+		return ConfirmPolicy(policy), nil
+	}
+	return ConfirmBusy, fmt.Errorf("unknown confirm policy %q, expected busy, always or never", policy)
+}
+
+// list is the list frame shown as frame.
+func (m *AppModel) list(frame int) *ListFrame {
+	switch frame {
+	case 2:
+		return &m.windows
+	case 3:
+		return &m.panes
+	}
+	return &m.sessions
+}
+
+// focusedList is the list frame that has focus.
+func (m *AppModel) focusedList() *ListFrame {
+	return m.list(m.focusedFrame)
+}
+
+// describeTargets names the given entities of frame, like
+// "2 windows: editor, logs".
+func (m *AppModel) describeTargets(frame int, ids []int) string {
+	kind := [...]string{"session", "window", "pane"}[frame-1]
+	list := m.list(frame)
+
+	names := []string{}
+	for _, id := range ids {
+		if item := list.ItemWithId(id); item != nil {
+			names = append(names, item.name)
+		}
+	}
+
+	if len(names) == 1 {
+		return fmt.Sprintf("%s %s", kind, names[0])
+	}
+	return fmt.Sprintf("%d %ss: %s", len(names), kind, strings.Join(names, ", "))
+}
+
+// needsConfirmation applies the confirm policy to a delete plan.
+func (m AppModel) needsConfirmation(plan deletePlanMsg) bool {
+	switch m.options.ConfirmPolicy {
+	case ConfirmAlways:
+		return true
+	case ConfirmNever:
+		return false
+	}
+	return plan.busy || len(plan.ids) > 1
+}
+
+// planDeleteCmd finds out what runs in the panes that deleting ids of frame
+// would kill.
+func planDeleteCmd(m AppModel, frame int, ids []int) tea.Cmd {
+	description := m.describeTargets(frame, ids)
+	return func() tea.Msg {
+		entities, err := m.client.ListEntities()
+		if err != nil {
+			return errorMsg{err}
+		}
+		entities.linkPaths()
+
+		windowOf := map[int]int{}
+		for _, window := range entities.Windows {
+			windowOf[window.id] = window.parent
+		}
+
+		panes := slices.DeleteFunc(slices.Clone(entities.Panes), func(pane TmuxEntity) bool {
+			switch frame {
+			case 1:
+				return !slices.Contains(ids, windowOf[pane.parent])
+			case 2:
+				return !slices.Contains(ids, pane.parent)
+			}
+			return !slices.Contains(ids, pane.id)
+		})
+
+		pids := []int{}
+		for _, pane := range panes {
+			pids = append(pids, pane.pane.PID)
+		}
+		counts, err := m.client.DescendantCounts(pids)
+		if err != nil {
+			// Still worth asking, just with less to tell
+			counts = map[int]int{}
+		}
+
+		plan := deletePlanMsg{frame: frame, ids: ids, description: description}
+		for _, pane := range panes {
+			detail := fmt.Sprintf("%s: %s", strings.Join(append(slices.Clone(pane.path), pane.name), "/"), pane.pane.CurrentCommand)
+			switch count := counts[pane.pane.PID]; count {
+			case 0:
+			case 1:
+				detail += " (1 child process)"
+			default:
+				detail += fmt.Sprintf(" (%d child processes)", count)
+			}
+			if !isShell(pane.pane.CurrentCommand) || counts[pane.pane.PID] > 0 {
+				plan.busy = true
+			}
+			plan.details = append(plan.details, detail)
+		}
+
+		return plan
+	}
+}
+
+// deleteCmd records what ids of frame contain, so it can be undone, and
+// then deletes them. Only what was actually deleted can be undone.
+func deleteCmd(m AppModel, frame int, ids []int, description string) tea.Cmd {
+	return func() tea.Msg {
+		undo, err := captureUndo(m.client, frame, ids)
+		if err != nil {
+			err = fmt.Errorf("%s can't be undone: %w", description, err)
+		}
+		undo.description = description
+
+		kill := m.client.KillSession
+		target := sessionTarget
+		switch frame {
+		case 2:
+			kill = m.client.KillWindow
+			target = windowTarget
+		case 3:
+			kill = m.client.KillPane
+			target = paneTarget
+		}
+
+		errs := []error{err}
+		killed := []int{}
+		for _, id := range ids {
+			if err := kill(target(id)); err != nil {
+				errs = append(errs, err)
+			} else {
+				killed = append(killed, id)
+			}
+		}
+		return deletedMsg{undo.only(killed), errors.Join(errs...)}
+	}
+}
+
+// pushUndo keeps entry for the undo key, forgetting the oldest ones.
+func (m *AppModel) pushUndo(entry undoEntry) {
+	if len(entry.sessions)+len(entry.windows)+len(entry.panes) == 0 {
+		return
+	}
+	m.undo = append(m.undo, entry)
+	if len(m.undo) > maxUndo {
+		m.undo = m.undo[len(m.undo)-maxUndo:]
+	}
+}
+
+func undoCmd(m AppModel, entry undoEntry) tea.Cmd {
+	return func() tea.Msg {
+		err := entry.restore(m.client)
+		return resultMsg(err, infoMsg("Restored "+entry.description))
+	}
+}
+
+func (confirmation Confirmation) RenderContents(theme Theme) Frame {
+	style := lipgloss.NewStyle().Foreground(theme.Foreground).Background(theme.Background)
+	return Frame{
+		title:    "Will be killed",
+		contents: style.Render(strings.Join(confirmation.details, "\n")),
+		focused:  true,
+	}
+}
@@ -0,0 +1,286 @@
+package tmux_tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ControlEvent is a notification sent by tmux to a control mode client, like
+// "%window-add @3". The name keeps its leading %. Payloads, such as the data
+// of %output, are not kept.
+type ControlEvent struct {
+	Name string
+	Args []string
+}
+
+// Subscription is a live stream of tmux notifications.
+type Subscription interface {
+	// Events is closed when the connection to tmux is lost.
+	Events() <-chan ControlEvent
+	// Follow attaches the subscription to session. tmux only reports
+	// %output and window changes for the session a client is attached to.
+	Follow(session string) error
+	Close() error
+}
+
+// How many arguments are kept for each notification. Anything after those is
+// a payload (pane output, new names, ...) that the TUI does not need.
+var controlEventArity = map[string]int{
+	"%output":                  1,
+	"%extended-output":         1,
+	"%pane-mode-changed":       1,
+	"%window-add":              1,
+	"%window-close":            1,
+	"%window-renamed":          1,
+	"%window-pane-changed":     2,
+	"%layout-change":           1,
+	"%unlinked-window-add":     1,
+	"%unlinked-window-close":   1,
+	"%unlinked-window-renamed": 1,
+	"%session-changed":         1,
+	"%session-renamed":         1,
+	"%session-window-changed":  2,
+	"%client-session-changed":  2,
+}
+
+func parseControlEvent(line string) ControlEvent {
+	name, rest, _ := strings.Cut(line, " ")
+	event := ControlEvent{Name: name}
+	arity := controlEventArity[name]
+	if arity > 0 && len(rest) > 0 {
+		event.Args = strings.SplitN(rest, " ", arity+1)
+		if len(event.Args) > arity {
+			event.Args = event.Args[:arity]
+		}
+	}
+	return event
+}
+
+type controlConnection struct {
+	stdin  io.WriteCloser
+	events chan ControlEvent
+	wait   func() error
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// Subscribe attaches a control mode client (tmux -C) to the server. The
+// client does not affect window sizes. It fails when there are no sessions to
+// attach to.
+func (client *ExecClient) Subscribe() (Subscription, error) {
+	// Remembers which client the user is on before our control client shows
+	// up, otherwise tmux could pick the latter as the target of switch-client.
+	if len(client.Client) == 0 {
+		name, err := client.output("display-message", "-p", "#{client_name}")
+		if err == nil {
+			client.Client = strings.TrimSpace(name)
+		}
+	}
+
+	c := client.command("-C", "attach-session", "-f", "ignore-size")
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+
+	connection := &controlConnection{
+		stdin:  stdin,
+		events: make(chan ControlEvent, 64),
+		wait:   c.Wait,
+	}
+
+	reader := bufio.NewReader(stdout)
+	first, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(first, "%begin") {
+		connection.Close()
+		return nil, fmt.Errorf("could not start control mode: %s", strings.TrimSpace(first))
+	}
+
+	go connection.read(reader)
+	return connection, nil
+}
+
+func (connection *controlConnection) read(reader io.Reader) {
+	defer close(connection.events)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	inBlock := true // The attach command's reply is still open
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "%begin"):
+			inBlock = true
+		case strings.HasPrefix(line, "%end"), strings.HasPrefix(line, "%error"):
+			inBlock = false
+		case inBlock || !strings.HasPrefix(line, "%"):
+			// Output of a command we sent
+		default:
+			event := parseControlEvent(line)
+			if event.Name == "%output" || event.Name == "%extended-output" {
+				// Output can be plentiful, drop it rather than block tmux
+				select {
+				case connection.events <- event:
+				default:
+				}
+			} else {
+				connection.events <- event
+			}
+			if event.Name == "%exit" {
+				return
+			}
+		}
+	}
+}
+
+func (connection *controlConnection) Events() <-chan ControlEvent {
+	return connection.events
+}
+
+func (connection *controlConnection) Follow(session string) error {
+	connection.mutex.Lock()
+	defer connection.mutex.Unlock()
+	if connection.closed {
+		return io.ErrClosedPipe
+	}
+	_, err := fmt.Fprintf(connection.stdin, "switch-client -t '%s'\n", session)
+	return err
+}
+
+func (connection *controlConnection) Close() error {
+	connection.mutex.Lock()
+	defer connection.mutex.Unlock()
+	if connection.closed {
+		return nil
+	}
+	connection.closed = true
+	// An empty line detaches a control client
+	fmt.Fprint(connection.stdin, "\n")
+	connection.stdin.Close()
+	return connection.wait()
+}
+
+type (
+	subscribedMsg     struct{ subscription Subscription }
+	controlEventMsg   ControlEvent
+	controlClosedMsg  struct{}
+	previewRefreshMsg struct{}
+	refreshMsg        struct{}
+)
+
+// How often the preview is captured at most, however often panes output
+// something or the selection changes.
+const previewRefreshDelay = 100 * time.Millisecond
+
+// subscribeCmd tries to open a control mode connection, falling back to
+// polling every second if it can't.
+func subscribeCmd(m AppModel) tea.Cmd {
+	return func() tea.Msg {
+		subscription, err := m.client.Subscribe()
+		if err != nil {
+			return tickMsg(time.Now())
+		}
+		return subscribedMsg{subscription}
+	}
+}
+
+func waitForEventCmd(subscription Subscription) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-subscription.Events()
+		if !ok {
+			return controlClosedMsg{}
+		}
+		return controlEventMsg(event)
+	}
+}
+
+func followCmd(subscription Subscription, session int) tea.Cmd {
+	return func() tea.Msg {
+		subscription.Follow(sessionTarget(session))
+		return nil
+	}
+}
+
+// handleControlEvent decides what has to be fetched again after tmux reports
+// a change.
+func (m *AppModel) handleControlEvent(event ControlEvent) tea.Cmd {
+	switch event.Name {
+	case "%output", "%extended-output":
+		if len(event.Args) == 0 {
+			return nil
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(event.Args[0], "%"))
+		if err != nil || !m.previewCovers(id) {
+			return nil
+		}
+		return m.refreshPreview()
+	case "%exit":
+		return nil
+	default:
+		return listEntitiesCmd(*m)
+	}
+}
+
+// previewCovers tells whether output in pane id shows up in the preview.
+func (m AppModel) previewCovers(id int) bool {
+	pane := m.panes.ItemWithId(id)
+	if pane == nil {
+		return false
+	}
+	switch m.focusedFrame {
+	case 1:
+		window := m.windows.ItemWithId(pane.parent)
+		return window != nil && window.parent == m.sessions.currentId
+	case 2:
+		return pane.parent == m.windows.currentId
+	case 3:
+		return pane.id == m.panes.currentId
+	}
+	return false
+}
+
+// refreshPreview captures the preview again, right away unless it was
+// captured less than previewRefreshDelay ago, in which case it is captured
+// once more when that time is up.
+func (m *AppModel) refreshPreview() tea.Cmd {
+	if m.previewPending {
+		m.previewStale = true
+		return nil
+	}
+	m.previewPending = true
+	return tea.Batch(previewCmd(*m), tea.Tick(previewRefreshDelay, func(time.Time) tea.Msg {
+		return previewRefreshMsg{}
+	}))
+}
+
+// previewSession is the session that contains what is being previewed.
+func (m AppModel) previewSession() int {
+	switch m.focusedFrame {
+	case 2:
+		if window := m.windows.ItemWithId(m.windows.currentId); window != nil {
+			return window.parent
+		}
+	case 3:
+		if pane := m.panes.ItemWithId(m.panes.currentId); pane != nil {
+			if window := m.windows.ItemWithId(pane.parent); window != nil {
+				return window.parent
+			}
+		}
+	}
+	return m.sessions.currentId
+}
@@ -0,0 +1,367 @@
+package tmux_tui
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// directoriesMsg has the ranked directories and the projects found under the
+// configured roots.
+type directoriesMsg struct {
+	entries  []DirectoryEntry
+	projects []string
+	err      error
+}
+
+// Once the ranks of all directories add up to more than this, they are
+// scaled down and the ones that fall below 1 are forgotten, like zoxide does.
+const maxDirectoryRank = 10000
+
+// DirectoryEntry is a directory sessions and windows were started in, ranked
+// by how often and how recently that happened.
+type DirectoryEntry struct {
+	Path       string    `yaml:"path"`
+	Rank       float64   `yaml:"rank"`
+	LastAccess time.Time `yaml:"last_access"`
+}
+
+func DirectoriesPath() string {
+	return filepath.Join(DataDir(), "directories.yaml")
+}
+
+func LoadDirectories() ([]DirectoryEntry, error) {
+	entries := []DirectoryEntry{}
+
+	bytes, err := os.ReadFile(DirectoriesPath())
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return entries, err
+	}
+
+	if err := yaml.Unmarshal(bytes, &entries); err != nil {
+		return entries, fmt.Errorf("could not parse %s: %w", DirectoriesPath(), err)
+	}
+
+	return entries, nil
+}
+
+// VisitDirectory ranks path up as a place sessions and windows start in.
+func VisitDirectory(path string) error {
+	entries, err := LoadDirectories()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	index := slices.IndexFunc(entries, func(entry DirectoryEntry) bool { return entry.Path == path })
+	if index == -1 {
+		entries = append(entries, DirectoryEntry{Path: path})
+		index = len(entries) - 1
+	}
+	entries[index].Rank++
+	entries[index].LastAccess = now
+
+	total := 0.0
+	for _, entry := range entries {
+		total += entry.Rank
+	}
+	if total > maxDirectoryRank {
+		for i := range entries {
+			entries[i].Rank *= 0.9 * maxDirectoryRank / total
+		}
+		entries = slices.DeleteFunc(entries, func(entry DirectoryEntry) bool { return entry.Rank < 1 })
+	}
+
+	bytes, err := yaml.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(DirectoriesPath()), 0o755); err != nil {
+		return err
+	}
+
+	// Written aside and renamed over, so that concurrent visits never leave
+	// a half written file behind
+	file, err := os.CreateTemp(filepath.Dir(DirectoriesPath()), "directories-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(bytes); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), DirectoriesPath())
+}
+
+// Frecency weighs the rank of the directory by how long ago it was last used.
+func (entry DirectoryEntry) Frecency(now time.Time) float64 {
+	age := now.Sub(entry.LastAccess)
+	switch {
+	case age < time.Hour:
+		return entry.Rank * 4
+	case age < 24*time.Hour:
+		return entry.Rank * 2
+	case age < 7*24*time.Hour:
+		return entry.Rank / 2
+	}
+	return entry.Rank / 4
+}
+
+// FindProjects finds the git repositories in roots and their subdirectories,
+// up to depth levels down. Repositories inside repositories and hidden
+// directories are not looked into.
+func FindProjects(roots []string, depth int) []string {
+	projects := []string{}
+
+	var walk func(dir string, level int)
+	walk = func(dir string, level int) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			projects = append(projects, dir)
+			return
+		}
+		if level >= depth {
+			return
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				walk(filepath.Join(dir, entry.Name()), level+1)
+			}
+		}
+	}
+
+	for _, root := range roots {
+		walk(expandPath("", root), 0)
+	}
+	return projects
+}
+
+// isPathInput tells whether input is written as a path rather than as a
+// query.
+func isPathInput(input string) bool {
+	return strings.HasPrefix(input, "/") || strings.HasPrefix(input, "~") || strings.HasPrefix(input, ".")
+}
+
+// completePath lists the directories input can be completed to: itself, if it
+// is one, followed by the subdirectories of its parent that start with its
+// last element. Hidden directories are only listed when asked for.
+func completePath(input string) []string {
+	path := expandPath("", input)
+	if !filepath.IsAbs(path) {
+		if cwd, err := os.Getwd(); err == nil {
+			path = filepath.Join(cwd, path)
+		}
+	}
+
+	completions := []string{}
+	parent, prefix := filepath.Dir(path), filepath.Base(path)
+	if strings.HasSuffix(input, "/") {
+		parent, prefix = path, ""
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		completions = append(completions, filepath.Clean(path))
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return completions
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		child := filepath.Join(parent, name)
+		if info, err := os.Stat(child); err == nil && info.IsDir() && !slices.Contains(completions, child) {
+			completions = append(completions, child)
+		}
+	}
+	return completions
+}
+
+// abbreviatePath writes path under the home directory with ~.
+func abbreviatePath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || len(home) == 0 {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if strings.HasPrefix(path, home+"/") {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+	return path
+}
+
+// projectSessionName is the name of the session of the project in dir, the
+// way tmux would store it.
+func projectSessionName(dir string) string {
+	return strings.NewReplacer(".", "_", ":", "_").Replace(filepath.Base(dir))
+}
+
+func loadDirectoriesCmd(m AppModel) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := LoadDirectories()
+		return directoriesMsg{entries, FindProjects(m.options.ProjectRoots, m.options.ProjectDepth), err}
+	}
+}
+
+// pickingDirectory tells whether the start directory of a new session or
+// window, or a project to open, is being asked for.
+func (m AppModel) pickingDirectory() bool {
+	return m.inputAction == SessionDirectory || m.inputAction == WindowDirectory || m.inputAction == OpenProject
+}
+
+// askDirectory asks for the start directory of the session or window named
+// name, which is created once it is picked, or for the project to open.
+func (m *AppModel) askDirectory(action InputAction, name string) tea.Cmd {
+	m.newName = name
+	m.inputAction = action
+	m.textInput.SetValue("")
+	m.updateDirectoryPicker(true)
+	return loadDirectoriesCmd(*m)
+}
+
+// directoryCandidates are the directories input could mean, best first, and
+// where each comes from. A path is completed, and anything else is matched
+// against the ranked directories, the ones panes are in and the projects,
+// ordered by frecency like zoxide does. "" is tmux's default directory.
+func (m AppModel) directoryCandidates(input string) ([]string, []string) {
+	paths := []string{}
+	sources := []string{}
+
+	if isPathInput(input) {
+		for _, path := range completePath(input) {
+			paths = append(paths, path)
+			sources = append(sources, "path")
+		}
+		return paths, sources
+	}
+
+	type candidate struct {
+		path     string
+		source   string
+		frecency float64
+		score    int
+	}
+	candidates := []candidate{}
+	seen := map[string]bool{"": true}
+	add := func(path string, source string, frecency float64) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		if score, _, ok := fuzzyMatch(input, abbreviatePath(path)); ok {
+			candidates = append(candidates, candidate{path, source, frecency, score})
+		}
+	}
+
+	now := time.Now()
+	for _, entry := range m.directories {
+		if info, err := os.Stat(entry.Path); err == nil && info.IsDir() {
+			add(entry.Path, "recent", entry.Frecency(now))
+		}
+	}
+	for _, pane := range m.panes.items {
+		add(pane.pane.CurrentPath, "open", 0)
+	}
+	for _, project := range m.projects {
+		add(project, "project", 0)
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if a.frecency != b.frecency {
+			return cmp.Compare(b.frecency, a.frecency)
+		}
+		return b.score - a.score
+	})
+
+	if len(input) == 0 && m.inputAction != OpenProject {
+		paths = append(paths, "")
+		sources = append(sources, "")
+	}
+	for _, candidate := range candidates {
+		paths = append(paths, candidate.path)
+		sources = append(sources, candidate.source)
+	}
+	return paths, sources
+}
+
+// updateDirectoryPicker lists the directories the input could mean, moving
+// the cursor back to the best one when reset.
+func (m *AppModel) updateDirectoryPicker(reset bool) {
+	paths, sources := m.directoryCandidates(m.textInput.Value())
+	_, height := m.previewSize()
+	if len(paths) > height {
+		paths, sources = paths[:max(1, height)], sources[:max(1, height)]
+	}
+
+	what := "the new session"
+	if m.inputAction == WindowDirectory {
+		what = "the new window"
+	}
+	if len(m.newName) > 0 {
+		what = m.newName
+	}
+
+	m.candidates = paths
+	m.picker.title = fmt.Sprintf("Start directory of %s", what)
+	if m.inputAction == OpenProject {
+		m.picker.title = "Open project"
+	}
+	m.picker.items = nil
+	for i, path := range paths {
+		if len(path) == 0 {
+			m.picker.items = append(m.picker.items, PickerItem{"Default", "chosen by tmux"})
+		} else {
+			m.picker.items = append(m.picker.items, PickerItem{abbreviatePath(path), sources[i]})
+		}
+	}
+	if reset {
+		m.picker.cursor = 0
+	}
+	m.picker.cursor = max(0, min(m.picker.cursor, len(paths)-1))
+}
+
+// selectedDirectory is the directory under the cursor, or "" when the input
+// is empty. It fails when nothing matches the input.
+func (m AppModel) selectedDirectory() (string, error) {
+	if m.picker.cursor < len(m.candidates) {
+		return m.candidates[m.picker.cursor], nil
+	}
+	if len(m.textInput.Value()) == 0 {
+		return "", nil
+	}
+	return "", fmt.Errorf("no directory matches %q", m.textInput.Value())
+}
+
+// completeDirectory replaces the input with the directory under the cursor,
+// to go on into its subdirectories.
+func (m *AppModel) completeDirectory() {
+	if m.picker.cursor >= len(m.candidates) || len(m.candidates[m.picker.cursor]) == 0 {
+		return
+	}
+	m.textInput.SetValue(strings.TrimSuffix(abbreviatePath(m.candidates[m.picker.cursor]), "/") + "/")
+	m.textInput.CursorEnd()
+	m.updateDirectoryPicker(true)
+}
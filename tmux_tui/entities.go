@@ -0,0 +1,195 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TmuxEntity is a session, window or pane. Exactly one of session, window
+// and pane is set, depending on which kind of entity it is.
+type TmuxEntity struct {
+	id     int
+	name   string
+	parent int
+	// path has the names of the entity's ancestors, outermost first.
+	path []string
+
+	session *SessionInfo
+	window  *WindowInfo
+	pane    *PaneInfo
+}
+
+type SessionInfo struct {
+	// Attached counts the clients attached to the session, not including
+	// control mode clients like our own.
+	Attached int
+	Created  time.Time
+	Activity time.Time
+	Group    string
+	// Path is the directory the session starts new windows in.
+	Path string
+}
+
+type WindowInfo struct {
+	Index    int
+	Layout   string
+	Active   bool
+	Zoomed   bool
+	Bell     bool
+	Activity bool
+	Silence  bool
+	// Synchronized is set when input goes to every pane of the window
+	Synchronized bool
+	// Linked is set when the window is in more than one session
+	Linked bool
+}
+
+type PaneInfo struct {
+	CurrentPath    string
+	CurrentCommand string
+	Title          string
+	PID            int
+	Width          int
+	Height         int
+	Dead           bool
+	InMode         bool
+	// Left and Top are the position of the pane in its window
+	Left   int
+	Top    int
+	Active bool
+}
+
+func newSession(id int, name string, info SessionInfo) TmuxEntity {
+	return TmuxEntity{id: id, name: name, parent: -1, session: &info}
+}
+
+func newWindow(id int, name string, session int, info WindowInfo) TmuxEntity {
+	return TmuxEntity{id: id, name: name, parent: session, window: &info}
+}
+
+func newPane(id int, name string, window int, info PaneInfo) TmuxEntity {
+	return TmuxEntity{id: id, name: name, parent: window, pane: &info}
+}
+
+// linkPaths fills in the path of every window and pane.
+func (entities *Entities) linkPaths() {
+	sessions := map[int]string{}
+	for _, session := range entities.Sessions {
+		sessions[session.id] = session.name
+	}
+	windows := map[int][]string{}
+	for i, window := range entities.Windows {
+		entities.Windows[i].path = []string{sessions[window.parent]}
+		windows[window.id] = []string{sessions[window.parent], window.name}
+	}
+	for i, pane := range entities.Panes {
+		entities.Panes[i].path = windows[pane.parent]
+	}
+}
+
+// sortWindows orders windows by session and then by index, which is the
+// order of the status bar. Panes follow their windows.
+func (entities *Entities) sortWindows() {
+	sessions := map[int]int{}
+	for i, session := range entities.Sessions {
+		sessions[session.id] = i
+	}
+	slices.SortStableFunc(entities.Windows, func(a, b TmuxEntity) int {
+		if order := sessions[a.parent] - sessions[b.parent]; order != 0 {
+			return order
+		}
+		return a.window.Index - b.window.Index
+	})
+
+	windows := map[int]int{}
+	for i, window := range entities.Windows {
+		windows[window.id] = i
+	}
+	slices.SortStableFunc(entities.Panes, func(a, b TmuxEntity) int {
+		return windows[a.parent] - windows[b.parent]
+	})
+}
+
+// Label is the number shown before the entity's name: the index for windows,
+// like in the status bar, and the id otherwise.
+func (entity TmuxEntity) Label() int {
+	if entity.window != nil {
+		return entity.window.Index
+	}
+	return entity.id
+}
+
+// Badges are the short markers shown next to the entity's name. Window flags
+// use the same characters as tmux's status line.
+func (entity TmuxEntity) Badges() []string {
+	badges := []string{}
+	switch {
+	case entity.session != nil:
+		switch entity.session.Attached {
+		case 0:
+		case 1:
+			badges = append(badges, "attached")
+		default:
+			badges = append(badges, fmt.Sprintf("%d attached", entity.session.Attached))
+		}
+		if len(entity.session.Group) > 0 {
+			badges = append(badges, "group:"+entity.session.Group)
+		}
+	case entity.window != nil:
+		flags := ""
+		if entity.window.Active {
+			flags += "*"
+		}
+		if entity.window.Activity {
+			flags += "#"
+		}
+		if entity.window.Bell {
+			flags += "!"
+		}
+		if entity.window.Silence {
+			flags += "~"
+		}
+		if entity.window.Zoomed {
+			flags += "Z"
+		}
+		if len(flags) > 0 {
+			badges = append(badges, flags)
+		}
+		if entity.window.Synchronized {
+			badges = append(badges, "sync")
+		}
+		if entity.window.Linked {
+			badges = append(badges, "linked")
+		}
+	case entity.pane != nil:
+		if entity.pane.Dead {
+			badges = append(badges, "dead")
+		}
+		if entity.pane.InMode {
+			badges = append(badges, "mode")
+		}
+		if len(entity.pane.CurrentPath) > 0 {
+			badges = append(badges, filepath.Base(entity.pane.CurrentPath))
+		}
+	}
+	return badges
+}
+
+// searchText is what the filter matches against: the name plus the metadata
+// that is worth looking for.
+func (entity TmuxEntity) searchText() string {
+	parts := []string{entity.name}
+	switch {
+	case entity.session != nil:
+		parts = append(parts, entity.session.Group)
+	case entity.window != nil:
+		parts = append(parts, entity.Badges()...)
+	case entity.pane != nil:
+		parts = append(parts, entity.pane.CurrentPath, entity.pane.Title)
+		parts = append(parts, entity.Badges()...)
+	}
+	return strings.Join(parts, " ")
+}
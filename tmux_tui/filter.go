@@ -0,0 +1,85 @@
+package tmux_tui
+
+import (
+	"strings"
+)
+
+// Prefixes that scope a filter term to a frame, indexed by frame number.
+var filterScopes = map[int]string{1: "s:", 2: "w:", 3: "p:"}
+
+// parseFilterQuery splits a query like "api s:work p:vim" into the filter of
+// each frame. Terms without a scope belong to the focused frame.
+func parseFilterQuery(query string, focusedFrame int) map[int]string {
+	terms := map[int][]string{}
+	for _, term := range strings.Fields(query) {
+		frame := focusedFrame
+		for f, prefix := range filterScopes {
+			if strings.HasPrefix(term, prefix) {
+				frame = f
+				term = strings.TrimPrefix(term, prefix)
+				break
+			}
+		}
+		if len(term) > 0 {
+			terms[frame] = append(terms[frame], term)
+		}
+	}
+
+	filters := map[int]string{}
+	for frame, frameTerms := range terms {
+		filters[frame] = strings.Join(frameTerms, " ")
+	}
+	return filters
+}
+
+// formatFilterQuery is the inverse of parseFilterQuery.
+func formatFilterQuery(filters map[int]string, focusedFrame int) string {
+	terms := strings.Fields(filters[focusedFrame])
+	for frame := 1; frame <= 3; frame++ {
+		if frame == focusedFrame {
+			continue
+		}
+		for _, term := range strings.Fields(filters[frame]) {
+			terms = append(terms, filterScopes[frame]+term)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// filters are the filters of each list, and of the whole tree at 0.
+func (m AppModel) filters() map[int]string {
+	return map[int]string{
+		0: m.treeFilter,
+		1: m.sessions.filterText,
+		2: m.windows.filterText,
+		3: m.panes.filterText,
+	}
+}
+
+func (m *AppModel) setFilters(filters map[int]string) {
+	m.treeFilter = filters[0]
+	m.sessions.filterText = filters[1]
+	m.windows.filterText = filters[2]
+	m.panes.filterText = filters[3]
+}
+
+func (m AppModel) hasFilters() bool {
+	return len(m.treeFilter) > 0 || len(m.sessions.filterText) > 0 || len(m.windows.filterText) > 0 || len(m.panes.filterText) > 0
+}
+
+// filterFrame is where terms without a scope go: the focused list, or the
+// whole tree, since its focus follows the cursor.
+func (m AppModel) filterFrame() int {
+	if m.treeView {
+		return 0
+	}
+	return m.focusedFrame
+}
+
+// filterTitle appends a frame's filter to its title.
+func filterTitle(title string, filter string) string {
+	if len(filter) == 0 {
+		return title
+	}
+	return title + " /" + filter
+}
@@ -16,6 +16,10 @@ type Frame struct {
 	width    int
 	height   int
 	focused  bool
+	// above and below are how many rows are scrolled out of view, which
+	// the borders point out.
+	above int
+	below int
 }
 
 func NewFrame(m AppModel) Frame {
@@ -35,10 +39,22 @@ func (frame Frame) View(theme Theme) string {
 	width := frame.width - 2
 	height := frame.height - 2
 
-	// Labeled top border
-	truncated := truncate.String(fmt.Sprintf(" %s ", frame.title), uint(width-2))
-	title := lipgloss.PlaceHorizontal(width-1, lipgloss.Left, truncated, lipgloss.WithWhitespaceChars(roundedBorder.Top))
-	borderTop := fmt.Sprintf("%s%s%s%s", roundedBorder.TopLeft, roundedBorder.Top, title, roundedBorder.TopRight)
+	// Labeled top border, with the scroll indicators on the right
+	up := ""
+	if frame.above > 0 {
+		up = fmt.Sprintf(" ↑ %d more %s", frame.above, roundedBorder.Top)
+	}
+	titleWidth := max(0, width-1-lipgloss.Width(up))
+	truncated := truncate.String(fmt.Sprintf(" %s ", frame.title), uint(max(0, titleWidth-1)))
+	title := lipgloss.PlaceHorizontal(titleWidth, lipgloss.Left, truncated, lipgloss.WithWhitespaceChars(roundedBorder.Top))
+	borderTop := fmt.Sprintf("%s%s%s%s%s", roundedBorder.TopLeft, roundedBorder.Top, title, up, roundedBorder.TopRight)
+
+	down := ""
+	if frame.below > 0 {
+		down = fmt.Sprintf(" ↓ %d more %s", frame.below, roundedBorder.Bottom)
+	}
+	down = lipgloss.PlaceHorizontal(width, lipgloss.Right, truncate.String(down, uint(width)), lipgloss.WithWhitespaceChars(roundedBorder.Bottom))
+	borderBottom := fmt.Sprintf("%s%s%s", roundedBorder.BottomLeft, down, roundedBorder.BottomRight)
 
 	style := lipgloss.NewStyle().
 		Background(theme.Background).
@@ -52,7 +68,8 @@ func (frame Frame) View(theme Theme) string {
 		Render(frame.contents)
 
 	header := style.SetString(borderTop)
-	pane := style.Border(lipgloss.RoundedBorder(), false, true, true, true).
+	footer := style.SetString(borderBottom)
+	pane := style.Border(lipgloss.RoundedBorder(), false, true, false, true).
 		Height(height).
 		PaddingLeft(1).
 		PaddingRight(1).
@@ -61,39 +78,109 @@ func (frame Frame) View(theme Theme) string {
 
 	if frame.focused {
 		header = header.Foreground(theme.Accent).BorderForeground(theme.Accent)
+		footer = footer.Foreground(theme.Accent)
 		pane = pane.Foreground(theme.Accent).BorderForeground(theme.Accent)
 	}
 
-	return lipgloss.JoinVertical(lipgloss.Top, header.String(), pane.String())
+	return lipgloss.JoinVertical(lipgloss.Top, header.String(), pane.String(), footer.String())
 }
 
-func (m AppModel) DrawGrid(preview, sessions, windows, frames, status Frame) string {
-	w := m.terminal.width
-	h := m.terminal.height
-	h60 := h * 6 / 10
-	h40 := h - h60
-	w33 := w / 3
-	lw33 := w - 2*w33
+// rect is an area of the screen, borders included.
+type rect struct {
+	x      int
+	y      int
+	width  int
+	height int
+}
+
+func (r rect) contains(x int, y int) bool {
+	return x >= r.x && x < r.x+r.width && y >= r.y && y < r.y+r.height
+}
 
-	preview.width = w
-	preview.height = h60 - 3 // Makes room for the status bar
-	sessions.width = w33
-	sessions.height = h40
-	windows.width = w33
-	windows.height = h40
-	frames.width = lw33
-	frames.height = h40
-	status.width = w
+// treeFrame is what frameAt returns for the tree, which takes the place of
+// the three lists.
+const treeFrame = 4
+
+// gridLayout is where DrawGrid puts each frame, which is also what mouse
+// events are matched against. lists are the areas of frames, which are the
+// lists shown: some of 1 to 3, or treeFrame. The preview is empty when it is
+// hidden.
+type gridLayout struct {
+	arrangement Arrangement
+	preview     rect
+	lists       []rect
+	frames      []int
+	status      rect
+}
+
+// frameAt is the frame at x, y: 0 for the preview, 1 to 3 for the lists,
+// treeFrame for the tree and -1 for anything else.
+func (layout gridLayout) frameAt(x int, y int) int {
+	if layout.preview.contains(x, y) {
+		return 0
+	}
+	for i, r := range layout.lists {
+		if r.contains(x, y) {
+			return layout.frames[i]
+		}
+	}
+	return -1
+}
+
+// list is the area of frame, from 1 to 3 or treeFrame, which is empty if it
+// is not shown.
+func (layout gridLayout) list(frame int) rect {
+	if i := slices.Index(layout.frames, frame); i != -1 {
+		return layout.lists[i]
+	}
+	return rect{}
+}
+
+// DrawGrid draws the preview, the lists and the status bar where the
+// arrangement puts them. lists are the sessions, windows and panes, or only
+// the tree.
+func (m AppModel) DrawGrid(preview Frame, lists []Frame, status Frame) string {
+	layout := m.gridLayout()
+
+	preview.width, preview.height = layout.preview.width, layout.preview.height
+	status.width = layout.status.width
 	status.height = 1
 
-	previewRendered := preview.View(m.theme)
-	sessionsRendered := sessions.View(m.theme)
-	windowsRendered := windows.View(m.theme)
-	framesRendered := frames.View(m.theme)
-	statusRendered := status.View(m.theme)
+	rendered := []string{}
+	for i, frame := range layout.frames {
+		list := lists[min(frame, len(lists))-1]
+		list.width, list.height = layout.lists[i].width, layout.lists[i].height
+		rendered = append(rendered, list.View(m.theme))
+	}
+
+	var body string
+	switch layout.arrangement {
+	case ArrangePreviewTop:
+		body = lipgloss.JoinVertical(lipgloss.Top, preview.View(m.theme), lipgloss.JoinHorizontal(lipgloss.Top, rendered...))
+	case ArrangePreviewRight:
+		body = lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.JoinVertical(lipgloss.Left, rendered...), preview.View(m.theme))
+	case ArrangePreviewHidden:
+		body = lipgloss.JoinHorizontal(lipgloss.Top, rendered...)
+	case ArrangeStacked:
+		body = lipgloss.JoinVertical(lipgloss.Left, rendered...)
+	case ArrangeZoomed:
+		if len(rendered) == 0 {
+			body = preview.View(m.theme)
+		} else {
+			body = rendered[0]
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Top, body, status.View(m.theme))
+}
 
-	horizontalBox := lipgloss.JoinHorizontal(lipgloss.Left, sessionsRendered, windowsRendered, framesRendered)
-	return lipgloss.JoinVertical(lipgloss.Top, previewRendered, horizontalBox, statusRendered)
+// previewSize is the room there is for the contents of the preview, none
+// when it is hidden.
+func (m AppModel) previewSize() (int, int) {
+	preview := m.gridLayout().preview
+	if preview.width == 0 {
+		return 0, 0
+	}
+	return preview.width - 6, preview.height - 2
 }
 
 type ListFrame struct {
@@ -103,9 +190,17 @@ type ListFrame struct {
 	markedIds  []int
 	parentId   int
 	filterText string
+	// offset is the first item in view, out of the rows that fit.
+	offset int
+	rows   int
 }
 
 func (listFrame *ListFrame) Update() {
+	// Forgets marks on items that are gone
+	listFrame.markedIds = slices.DeleteFunc(listFrame.markedIds, func(id int) bool {
+		return listFrame.ItemWithId(id) == nil
+	})
+
 	visibleItems := listFrame.visibleItems()
 	for _, item := range visibleItems {
 		if item.id == listFrame.currentId {
@@ -125,13 +220,28 @@ func (listFrame *ListFrame) RenderContents(theme Theme) Frame {
 
 	currentIndex := -1
 
+	badgeStyle := itemStyle.Foreground(theme.Secondary)
+
+	matches := listFrame.visibleMatches()
+	end := len(matches)
+	if listFrame.rows > 0 {
+		end = min(end, listFrame.offset+listFrame.rows)
+	}
+	start := min(listFrame.offset, end)
+
 	l := list.New().EnumeratorStyle(enumeratorStyle).ItemStyle(itemStyle)
-	for i, item := range listFrame.visibleItems() {
+	for i, match := range matches[start:end] {
+		item := match.item
+		nameStyle := itemStyle
 		if slices.Contains(listFrame.markedIds, item.id) {
-			l.Item(itemStyle.Foreground(theme.Secondary).Render(fmt.Sprintf("[%d]: %s", item.id, item.name)))
-		} else {
-			l.Item(itemStyle.Render(fmt.Sprintf("[%d]: %s", item.id, item.name)))
+			nameStyle = itemStyle.Foreground(theme.Secondary)
 		}
+		label := nameStyle.Render(fmt.Sprintf("[%d]: ", item.Label()))
+		label += highlight(item.name, match.positions, nameStyle, nameStyle.Foreground(theme.Accent).Bold(true))
+		for _, badge := range item.Badges() {
+			label += itemStyle.Render(" ") + badgeStyle.Render(badge)
+		}
+		l.Item(label)
 		if item.id == listFrame.currentId {
 			currentIndex = i
 		}
@@ -147,42 +257,57 @@ func (listFrame *ListFrame) RenderContents(theme Theme) Frame {
 		l = l.Enumerator(enumerator)
 	}
 
-	listFrame.frame.contents = l.String()
-	return listFrame.frame
+	frame := listFrame.frame
+	frame.contents = l.String()
+	frame.above = start
+	frame.below = len(matches) - end
+	return frame
 }
 
-func (listFrame *ListFrame) SelectNext() {
+// ItemAt is the item shown at row of the list, counting from the first one
+// in view.
+func (listFrame *ListFrame) ItemAt(row int) *TmuxEntity {
 	items := listFrame.visibleItems()
-	if listFrame.currentId == -1 && len(items) > 0 {
-		listFrame.currentId = 0
+	row += listFrame.offset
+	if row < listFrame.offset || row >= len(items) {
+		return nil
+	}
+	return &items[row]
+}
+
+// Scroll keeps the selected item in view of a list where rows items fit.
+func (listFrame *ListFrame) Scroll(rows int) {
+	listFrame.rows = rows
+	if rows <= 0 {
 		return
 	}
-	for i, item := range items {
-		if item.id == listFrame.currentId {
-			if i+1 < len(items) {
-				listFrame.currentId = items[i+1].id
-			}
-			return
-		}
+	items := listFrame.visibleItems()
+	index := slices.IndexFunc(items, func(item TmuxEntity) bool { return item.id == listFrame.currentId })
+	if index != -1 && index < listFrame.offset {
+		listFrame.offset = index
+	} else if index != -1 && index >= listFrame.offset+rows {
+		listFrame.offset = index - rows + 1
 	}
-	listFrame.currentId = -1
+	listFrame.offset = max(0, min(listFrame.offset, len(items)-rows))
 }
 
-func (listFrame *ListFrame) SelectPrevious() {
+// Move selects the item delta rows away from the selected one, stopping at
+// the first and the last.
+func (listFrame *ListFrame) Move(delta int) {
 	items := listFrame.visibleItems()
-	if listFrame.currentId == -1 && len(items) > 0 {
-		listFrame.currentId = items[len(items)-1].id
+	if len(items) == 0 {
 		return
 	}
-	for i, item := range items {
-		if item.id == listFrame.currentId {
-			if i-1 >= 0 {
-				listFrame.currentId = items[i-1].id
-			}
-			return
-		}
+	index := slices.IndexFunc(items, func(item TmuxEntity) bool { return item.id == listFrame.currentId })
+	switch {
+	case index != -1:
+		index = max(0, min(len(items)-1, index+delta))
+	case delta > 0:
+		index = 0
+	default:
+		index = len(items) - 1
 	}
-	listFrame.currentId = -1
+	listFrame.currentId = items[index].id
 }
 
 func (listFrame *ListFrame) MarkSelection() {
@@ -192,10 +317,53 @@ func (listFrame *ListFrame) MarkSelection() {
 func (listFrame *ListFrame) UnmarkSelection() {
 	index := slices.Index(listFrame.markedIds, listFrame.currentId)
 	if index != -1 {
-		listFrame.markedIds = slices.Delete(listFrame.markedIds, index, 1)
+		listFrame.markedIds = slices.Delete(listFrame.markedIds, index, index+1)
+	}
+}
+
+func (listFrame *ListFrame) ToggleMark() {
+	if listFrame.currentId == -1 {
+		return
+	}
+	if listFrame.IsMarked(listFrame.currentId) {
+		listFrame.UnmarkSelection()
+	} else {
+		listFrame.MarkSelection()
+	}
+}
+
+// MarkVisible marks every item that is currently shown.
+func (listFrame *ListFrame) MarkVisible() {
+	for _, item := range listFrame.visibleItems() {
+		if !listFrame.IsMarked(item.id) {
+			listFrame.markedIds = append(listFrame.markedIds, item.id)
+		}
 	}
 }
 
+// InvertMarks toggles the mark of every item that is currently shown.
+func (listFrame *ListFrame) InvertMarks() {
+	for _, item := range listFrame.visibleItems() {
+		if index := slices.Index(listFrame.markedIds, item.id); index != -1 {
+			listFrame.markedIds = slices.Delete(listFrame.markedIds, index, index+1)
+		} else {
+			listFrame.markedIds = append(listFrame.markedIds, item.id)
+		}
+	}
+}
+
+// Targets are the ids an action applies to: the marked items or, if there
+// are none, the selected one.
+func (listFrame *ListFrame) Targets() []int {
+	if len(listFrame.markedIds) > 0 {
+		return slices.Clone(listFrame.markedIds)
+	}
+	if listFrame.currentId == -1 {
+		return nil
+	}
+	return []int{listFrame.currentId}
+}
+
 func (listFrame *ListFrame) IsMarked(id int) bool {
 	return slices.Contains(listFrame.markedIds, id)
 }
@@ -213,15 +381,71 @@ func (listFrame ListFrame) ItemWithId(id int) *TmuxEntity {
 	return nil
 }
 
-func (listFrame *ListFrame) visibleItems() []TmuxEntity {
-	var items []TmuxEntity
-	filter := strings.ToLower(listFrame.filterText)
+type listMatch struct {
+	item      TmuxEntity
+	score     int
+	positions []int
+}
+
+// matchFilter matches item against every term of filter.
+func matchFilter(filter string, item TmuxEntity) (listMatch, bool) {
+	match := listMatch{item: item}
+	for _, term := range strings.Fields(filter) {
+		score, positions, ok := hierarchicalMatch(term, item)
+		if !ok {
+			return match, false
+		}
+		match.score += score
+		match.positions = append(match.positions, positions...)
+	}
+	return match, true
+}
+
+// visibleMatches returns the items under the current parent that match the
+// filter, best matches first.
+func (listFrame *ListFrame) visibleMatches() []listMatch {
+	var matches []listMatch
 	for _, item := range listFrame.items {
-		matchesParent := listFrame.parentId == -1 || item.parent == listFrame.parentId
-		matchesFilter := len(filter) == 0 || strings.Contains(strings.ToLower(item.name), filter)
-		if matchesParent && matchesFilter {
-			items = append(items, item)
+		if listFrame.parentId != -1 && item.parent != listFrame.parentId {
+			continue
+		}
+		if match, ok := matchFilter(listFrame.filterText, item); ok {
+			matches = append(matches, match)
 		}
 	}
+	slices.SortStableFunc(matches, func(a, b listMatch) int {
+		return b.score - a.score
+	})
+	return matches
+}
+
+func (listFrame *ListFrame) visibleItems() []TmuxEntity {
+	var items []TmuxEntity
+	for _, match := range listFrame.visibleMatches() {
+		items = append(items, match.item)
+	}
 	return items
 }
+
+// highlight renders text with the runes at positions in matchStyle.
+func highlight(text string, positions []int, style, matchStyle lipgloss.Style) string {
+	if len(positions) == 0 {
+		return style.Render(text)
+	}
+	var builder strings.Builder
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		matched := slices.Contains(positions, start)
+		end := start + 1
+		for end < len(runes) && slices.Contains(positions, end) == matched {
+			end++
+		}
+		if matched {
+			builder.WriteString(matchStyle.Render(string(runes[start:end])))
+		} else {
+			builder.WriteString(style.Render(string(runes[start:end])))
+		}
+		start = end
+	}
+	return builder.String()
+}
@@ -0,0 +1,162 @@
+package tmux_tui
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scores used by fuzzyMatch, loosely following fzf's.
+const (
+	scoreMatch       = 16
+	scoreGapStart    = -3
+	scoreGapExtend   = -1
+	bonusBoundary    = 8
+	bonusConsecutive = 4
+	bonusFirstChar   = 8
+)
+
+// fuzzyMatch tells whether all runes of pattern appear in text in order,
+// ignoring case. It returns a score, higher being better, and the rune
+// positions in text that matched. An empty pattern matches everything.
+func fuzzyMatch(pattern string, text string) (int, []int, bool) {
+	// Lowered rune by rune, so that positions in haystack are positions in
+	// text
+	needle := []rune(pattern)
+	for i, r := range needle {
+		needle[i] = unicode.ToLower(r)
+	}
+	haystack := []rune(text)
+	for i, r := range haystack {
+		haystack[i] = unicode.ToLower(r)
+	}
+	original := []rune(text)
+
+	if len(needle) == 0 {
+		return 0, nil, true
+	}
+
+	// Finds where the first full match ends...
+	end := -1
+	n := 0
+	for i, r := range haystack {
+		if r == needle[n] {
+			n++
+			if n == len(needle) {
+				end = i
+				break
+			}
+		}
+	}
+	if end == -1 {
+		return 0, nil, false
+	}
+
+	// ...then walks back from there to find the shortest match
+	start := end
+	n = len(needle) - 1
+	for i := end; i >= 0; i-- {
+		if haystack[i] == needle[n] {
+			n--
+			if n < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	positions := make([]int, 0, len(needle))
+	score := 0
+	n = 0
+	inGap := false
+	for i := start; i <= end && n < len(needle); i++ {
+		if haystack[i] != needle[n] {
+			if inGap {
+				score += scoreGapExtend
+			} else {
+				score += scoreGapStart
+				inGap = true
+			}
+			continue
+		}
+
+		score += scoreMatch
+		if isWordBoundary(original, i) {
+			if n == 0 {
+				score += bonusFirstChar
+			}
+			score += bonusBoundary
+		}
+		if len(positions) > 0 && positions[len(positions)-1] == i-1 {
+			score += bonusConsecutive
+		}
+
+		positions = append(positions, i)
+		inGap = false
+		n++
+	}
+
+	// Prefers matches close to the start of the text
+	score -= min(start, 10)
+
+	return score, positions, true
+}
+
+func isWordBoundary(text []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	previous, current := text[i-1], text[i]
+	switch {
+	case strings.ContainsRune(" /-_.:@", previous):
+		return true
+	case unicode.IsLower(previous) && unicode.IsUpper(current):
+		return true
+	case !unicode.IsDigit(previous) && unicode.IsDigit(current):
+		return true
+	}
+	return false
+}
+
+// hierarchicalMatch matches query against an entity. The query may be a path
+// like "api/logs": the last part is matched against the entity and the ones
+// before it against its ancestors, in order. Only the positions matched in
+// the entity's own name are returned.
+func hierarchicalMatch(query string, entity TmuxEntity) (int, []int, bool) {
+	parts := strings.Split(query, "/")
+	own := parts[len(parts)-1]
+	ancestors := parts[:len(parts)-1]
+
+	score := 0
+	next := 0
+	for _, part := range ancestors {
+		if len(part) == 0 {
+			continue
+		}
+		matched := false
+		for next < len(entity.path) {
+			s, _, ok := fuzzyMatch(part, entity.path[next])
+			next++
+			if ok {
+				score += s
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return 0, nil, false
+		}
+	}
+
+	s, positions, ok := fuzzyMatch(own, entity.name)
+	if ok {
+		return score + s, positions, true
+	}
+
+	// Metadata like paths and flags can be searched too, but ranks lower
+	s, _, ok = fuzzyMatch(own, entity.searchText())
+	if ok {
+		return score + s/2, nil, true
+	}
+
+	return 0, nil, false
+}
@@ -0,0 +1,13 @@
+package tmux_tui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyMatchIgnoresCaseOfAnyLetter(t *testing.T) {
+	_, positions, ok := fuzzyMatch("éà", "CAFÉ ÀLA")
+	if !ok || !slices.Equal(positions, []int{3, 5}) {
+		t.Errorf("matched %v at %v, expected a match at [3 5]", ok, positions)
+	}
+}
@@ -0,0 +1,172 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var modeTitles = map[string]string{
+	NormalMode:     "Lists",
+	SwapMode:       "Swapping",
+	MoveMode:       "Moving",
+	LayoutMode:     "Layout editor",
+	ScrollbackMode: "Scrollback",
+	SearchMode:     "Search results",
+	PickerMode:     "Pickers",
+	ConfirmMode:    "Confirmations",
+	ErrorsMode:     "Error history",
+	HelpMode:       "Help",
+	InputMode:      "Text input",
+}
+
+var frameNames = map[int]string{1: "sessions", 2: "windows", 3: "panes"}
+
+// helpLines lists the bindings of every mode, the way they are configured,
+// with the action names the configuration uses.
+func (m AppModel) helpLines() []string {
+	normalStyle := lipgloss.NewStyle().Foreground(m.theme.Foreground).Background(m.theme.Background)
+	titleStyle := normalStyle.Foreground(m.theme.Accent).Bold(true)
+	framesStyle := normalStyle.Foreground(m.theme.Secondary)
+
+	lines := []string{}
+	for _, mode := range Modes {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, titleStyle.Render(modeTitles[mode]))
+		for _, binding := range m.options.Keys[mode] {
+			keys := []string{}
+			for _, k := range binding.Keys() {
+				keys = append(keys, displayKey(k))
+			}
+			line := normalStyle.Render(fmt.Sprintf("  %-24s ", strings.Join(keys, " "))) +
+				framesStyle.Render(fmt.Sprintf("%-18s ", binding.Action)) +
+				normalStyle.Render(binding.Help().Desc)
+			if len(binding.Frames) > 0 {
+				frames := []string{}
+				for _, frame := range binding.Frames {
+					frames = append(frames, frameNames[frame])
+				}
+				line += framesStyle.Render(fmt.Sprintf(" (%s)", strings.Join(frames, ", ")))
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Help is the full screen list of key bindings, scrolled by helpOffset.
+func (m AppModel) Help() Frame {
+	frame := NewFrame(m)
+	frame.title = fmt.Sprintf("Help (close: %s)", m.options.Keys.Key(HelpMode, "close"))
+
+	lines := m.helpLines()
+	offset := max(0, min(m.helpOffset, len(lines)-(m.terminal.height-2)))
+	frame.contents = strings.Join(lines[offset:], "\n")
+	return frame
+}
+
+// keyMode is the mode keys are handled in, and the frame whose actions
+// apply.
+func (m AppModel) keyMode() (string, int) {
+	switch {
+	case m.showErrors:
+		return ErrorsMode, m.focusedFrame
+	case m.showHelp:
+		return HelpMode, m.focusedFrame
+	case m.confirmation != nil:
+		return ConfirmMode, m.focusedFrame
+	case m.swapSrc != -1:
+		return SwapMode, m.focusedFrame
+	case len(m.moveSrcs) > 0:
+		return MoveMode, m.moveFrame
+	case m.pickerAction != PickNothing:
+		return PickerMode, m.focusedFrame
+	case m.inputAction != None:
+		return InputMode, m.focusedFrame
+	case m.search != nil:
+		return SearchMode, m.focusedFrame
+	case m.scrollback != nil:
+		return ScrollbackMode, m.focusedFrame
+	case m.layoutMode:
+		return LayoutMode, m.focusedFrame
+	}
+	return NormalMode, m.focusedFrame
+}
+
+// hints are the status bar entries of the bindings of the current mode that
+// can be used now. The main action of a mode and the toggles that are on are
+// highlighted.
+func (m AppModel) hints(normalStyle lipgloss.Style, accentStyle lipgloss.Style) []string {
+	keys := m.options.Keys
+	mode, frame := m.keyMode()
+
+	hints := []string{}
+	render := func(binding Binding, style lipgloss.Style) {
+		hints = append(hints, style.Render(fmt.Sprintf("%s: %s", binding.Help().Desc, binding.Help().Key)))
+	}
+
+	// These fall through to the navigation of normal mode
+	if mode == SwapMode || mode == MoveMode || mode == LayoutMode {
+		render(*keys.Binding(NormalMode, "quit"), normalStyle)
+	}
+
+	highlighted := mode == NormalMode
+	for _, binding := range keys[mode] {
+		if !binding.Hint || !binding.AppliesTo(frame) {
+			continue
+		}
+
+		style := normalStyle
+		switch binding.Action {
+		case "quit":
+		case "undo":
+			if len(m.undo) == 0 {
+				continue
+			}
+		case "errors":
+			if len(m.errors) == 0 {
+				continue
+			}
+		case "next-match", "previous-match":
+			if m.scrollback == nil || m.scrollback.pattern == nil {
+				continue
+			}
+		case "collapse", "expand":
+			if !m.treeView {
+				continue
+			}
+		case "tree":
+			if m.treeView {
+				style = accentStyle
+			}
+		case "show-all":
+			if m.treeView {
+				continue
+			}
+			if m.showAll {
+				style = accentStyle
+			}
+		case "filter":
+			if m.hasFilters() {
+				style = accentStyle
+			}
+		default:
+			if !highlighted {
+				style = accentStyle
+				highlighted = true
+			}
+		}
+		render(binding, style)
+
+		if mode == NormalMode && binding.Action == "quit" {
+			if marked := len(m.focusedList().markedIds); marked > 0 {
+				hints = append(hints, accentStyle.Render(fmt.Sprintf("Marked: %d", marked)))
+			}
+		}
+	}
+
+	return hints
+}
@@ -0,0 +1,331 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Modes that have their own key bindings. Swap, move and layout mode also use
+// the navigation bindings of normal mode.
+const (
+	NormalMode     = "normal"
+	SwapMode       = "swap"
+	MoveMode       = "move"
+	LayoutMode     = "layout"
+	ScrollbackMode = "scrollback"
+	SearchMode     = "search"
+	PickerMode     = "picker"
+	ConfirmMode    = "confirm"
+	ErrorsMode     = "errors"
+	HelpMode       = "help"
+	InputMode      = "input"
+)
+
+// Modes in the order they are shown in the help.
+var Modes = []string{NormalMode, SwapMode, MoveMode, LayoutMode, ScrollbackMode, SearchMode, PickerMode, ConfirmMode, ErrorsMode, HelpMode, InputMode}
+
+// Binding is an action of a mode, the keys that trigger it and its help.
+type Binding struct {
+	key.Binding
+	Action string
+	// Frames are the lists the action works on, all of them when empty. In
+	// move mode it is the list of the item being moved.
+	Frames []int
+	// Hint shows the binding in the status bar. All of them are in the help.
+	Hint bool
+}
+
+// KeyMap has the bindings of each mode, in the order they are shown. Keys
+// are named the way bubbletea names them, like "ctrl+c", "enter" or "K",
+// with "space" for the space bar.
+type KeyMap map[string][]Binding
+
+// The bindings of normal mode that swap, move and layout mode fall through to.
+var navigationActions = []string{"quit", "up", "down", "page-up", "page-down", "half-page-up", "half-page-down", "top", "bottom", "show-all"}
+
+// focusFrames are the frames the focus actions go to.
+var focusFrames = map[string]int{"focus-sessions": 1, "focus-windows": 2, "focus-panes": 3}
+
+func bind(action string, help string, keys ...string) Binding {
+	binding := Binding{Action: action}
+	binding.SetHelp("", help)
+	binding.SetKeys(keys...)
+	return binding
+}
+
+// SetKeys binds keys to the action, keeping its help.
+func (binding *Binding) SetKeys(keys ...string) {
+	for i, k := range keys {
+		if k == "space" {
+			keys[i] = " "
+		}
+	}
+	binding.Binding.SetKeys(keys...)
+	if len(keys) > 0 {
+		binding.SetHelp(displayKey(keys[0]), binding.Help().Desc)
+	}
+}
+
+// in limits the binding to frames.
+func (binding Binding) in(frames ...int) Binding {
+	binding.Frames = frames
+	return binding
+}
+
+// hinted shows the binding in the status bar.
+func (binding Binding) hinted() Binding {
+	binding.Hint = true
+	return binding
+}
+
+// AppliesTo tells whether the binding works on items of frame.
+func (binding Binding) AppliesTo(frame int) bool {
+	return len(binding.Frames) == 0 || slices.Contains(binding.Frames, frame)
+}
+
+// keyName is how k is written in the configuration.
+func keyName(k string) string {
+	if k == " " {
+		return "space"
+	}
+	return k
+}
+
+// displayKey is how k is shown in hints and in the help.
+func displayKey(k string) string {
+	k = keyName(k)
+	if len([]rune(k)) > 1 {
+		return "<" + k + ">"
+	}
+	return k
+}
+
+var DefaultKeyMap = KeyMap{
+	NormalMode: {
+		bind("quit", "Quit", "q", "ctrl+c").hinted(),
+		bind("go-to", "Go to", "enter").hinted(),
+		bind("delete", "Delete", "d").hinted(),
+		bind("mark", "Mark", "space").hinted(),
+		bind("swap", "Swap", "s").in(2, 3).hinted(),
+		bind("move", "Move", "m").in(2, 3).hinted(),
+		bind("undo", "Undo", "u").hinted(),
+		bind("new", "New", "n").in(1, 2).hinted(),
+		bind("new-nameless", "New (nameless)", "N").in(1, 2).hinted(),
+		bind("open", "Open project", "o").hinted(),
+		bind("rename", "Rename", "r").in(1, 2).hinted(),
+		bind("renumber", "Renumber", "R").in(1, 2).hinted(),
+		bind("move-up", "Move up", "K").in(2).hinted(),
+		bind("move-down", "Move down", "J").in(2).hinted(),
+		bind("split-vertical", "Vertical split", "v").in(3).hinted(),
+		bind("split-horizontal", "Horizontal split", "h").in(3).hinted(),
+		bind("break-pane", "Break out", "b").in(3).hinted(),
+		bind("layout", "Layout", "L").in(2, 3).hinted(),
+		bind("synchronize", "Synchronize", "Y").in(2, 3).hinted(),
+		bind("send-command", "Send command", "c").hinted(),
+		bind("send-keys", "Send keys", "C").hinted(),
+		bind("show-all", "Show all", "a").hinted(),
+		bind("filter", "Filter", "/").hinted(),
+		bind("search-panes", "Search panes", "F").hinted(),
+		bind("errors", "Errors", "E").hinted(),
+		bind("tree", "Tree", "T").hinted(),
+		bind("collapse", "Collapse", "left").hinted(),
+		bind("expand", "Expand", "right").hinted(),
+		bind("arrange", "Next arrangement", "V"),
+		bind("up", "Up", "k", "up", "ctrl+p"),
+		bind("down", "Down", "j", "down", "ctrl+n"),
+		bind("page-up", "Page up", "pgup"),
+		bind("page-down", "Page down", "pgdown"),
+		bind("half-page-up", "Half a page up", "ctrl+u"),
+		bind("half-page-down", "Half a page down", "ctrl+d"),
+		bind("top", "Top", "g", "home"),
+		bind("bottom", "Bottom", "G", "end"),
+		bind("back", "Clear marks or quit", "esc"),
+		bind("scrollback", "Scroll the preview", "0"),
+		bind("focus-sessions", "Focus sessions", "1"),
+		bind("focus-windows", "Focus windows", "2"),
+		bind("focus-panes", "Focus panes", "3"),
+		bind("mark-all", "Mark all", "A"),
+		bind("invert-marks", "Invert marks", "I"),
+		bind("snapshot", "Save snapshot", "S"),
+		bind("dismiss", "Dismiss message", "x"),
+		bind("help", "Help", "?"),
+	},
+	SwapMode: {
+		bind("swap", "Swap", "s", "space", "enter").hinted(),
+		bind("cancel", "Cancel", "esc").hinted(),
+	},
+	MoveMode: {
+		bind("move", "Move here", "m", "space", "enter").in(2).hinted(),
+		bind("link", "Link here", "l").in(2).hinted(),
+		bind("join-vertical", "Join vertically", "v").in(3).hinted(),
+		bind("join-horizontal", "Join horizontally", "h").in(3).hinted(),
+		bind("cancel", "Cancel", "esc").hinted(),
+		bind("focus-sessions", "Focus sessions", "1"),
+		bind("focus-windows", "Focus windows", "2"),
+		bind("focus-panes", "Focus panes", "3"),
+	},
+	LayoutMode: {
+		bind("next-preset", "Next layout", "space").hinted(),
+		bind("zoom", "Zoom", "z").hinted(),
+		bind("save", "Save", "S").hinted(),
+		bind("load", "Load", "l").hinted(),
+		bind("back", "Done", "esc").hinted(),
+		bind("grow-left", "Grow left", "H").hinted(),
+		bind("grow-down", "Grow down", "J").hinted(),
+		bind("grow-up", "Grow up", "K").hinted(),
+		bind("grow-right", "Grow right", "L").hinted(),
+		bind("dismiss", "Dismiss message", "x"),
+		bind("help", "Help", "?"),
+	},
+	ScrollbackMode: {
+		bind("quit", "Quit", "q", "ctrl+c").hinted(),
+		bind("search", "Search", "/").hinted(),
+		bind("next-match", "Next match", "n").hinted(),
+		bind("previous-match", "Previous match", "N").hinted(),
+		bind("select", "Select", "v", "space").hinted(),
+		bind("copy", "Copy", "y", "enter").hinted(),
+		bind("top", "Top", "g", "home").hinted(),
+		bind("bottom", "Bottom", "G", "end").hinted(),
+		bind("back", "Back", "esc").hinted(),
+		bind("down", "Down", "j", "down", "ctrl+n"),
+		bind("up", "Up", "k", "up", "ctrl+p"),
+		bind("half-page-down", "Half a page down", "ctrl+d"),
+		bind("half-page-up", "Half a page up", "ctrl+u"),
+		bind("page-down", "Page down", "pgdown"),
+		bind("page-up", "Page up", "pgup"),
+		bind("focus-sessions", "Focus sessions", "1"),
+		bind("focus-windows", "Focus windows", "2"),
+		bind("focus-panes", "Focus panes", "3"),
+		bind("dismiss", "Dismiss message", "x"),
+		bind("help", "Help", "?"),
+	},
+	SearchMode: {
+		bind("quit", "Quit", "q", "ctrl+c").hinted(),
+		bind("go-to", "Go to", "enter").hinted(),
+		bind("search", "New search", "F").hinted(),
+		bind("back", "Close", "esc").hinted(),
+		bind("down", "Down", "j", "down", "ctrl+n"),
+		bind("up", "Up", "k", "up", "ctrl+p"),
+		bind("half-page-down", "Half a page down", "ctrl+d"),
+		bind("half-page-up", "Half a page up", "ctrl+u"),
+		bind("dismiss", "Dismiss message", "x"),
+		bind("help", "Help", "?"),
+	},
+	PickerMode: {
+		bind("select", "Select", "enter").hinted(),
+		bind("cancel", "Cancel", "esc").hinted(),
+		bind("up", "Up", "k", "up", "ctrl+p"),
+		bind("down", "Down", "j", "down", "ctrl+n"),
+		bind("quit", "Quit", "ctrl+c"),
+	},
+	ConfirmMode: {
+		bind("yes", "Yes", "y", "Y", "enter"),
+		bind("no", "No", "n", "N", "q", "esc"),
+		bind("quit", "Quit", "ctrl+c"),
+	},
+	ErrorsMode: {
+		bind("close", "Close", "esc", "E", "q"),
+		bind("quit", "Quit", "ctrl+c"),
+	},
+	HelpMode: {
+		bind("close", "Close", "esc", "?", "q"),
+		bind("down", "Down", "j", "down", "ctrl+n"),
+		bind("up", "Up", "k", "up", "ctrl+p"),
+		bind("quit", "Quit", "ctrl+c"),
+	},
+	InputMode: {
+		bind("accept", "Accept", "enter"),
+		bind("cancel", "Cancel", "esc"),
+		bind("complete", "Complete the directory", "tab"),
+		bind("up", "Previous directory", "up", "ctrl+p"),
+		bind("down", "Next directory", "down", "ctrl+n"),
+	},
+}
+
+// Action is the action that msg is bound to in mode, or "" if none is.
+func (keys KeyMap) Action(mode string, msg tea.KeyMsg) string {
+	for _, binding := range keys[mode] {
+		if key.Matches(msg, binding.Binding) {
+			return binding.Action
+		}
+	}
+	return ""
+}
+
+// Binding is the binding of action in mode.
+func (keys KeyMap) Binding(mode string, action string) *Binding {
+	for i, binding := range keys[mode] {
+		if binding.Action == action {
+			return &keys[mode][i]
+		}
+	}
+	return nil
+}
+
+// Key is the first key of action in mode, as shown in hints.
+func (keys KeyMap) Key(mode string, action string) string {
+	if binding := keys.Binding(mode, action); binding != nil {
+		return binding.Help().Key
+	}
+	return ""
+}
+
+func (keys KeyMap) actions(mode string) []string {
+	actions := []string{}
+	for _, binding := range keys[mode] {
+		actions = append(actions, binding.Action)
+	}
+	slices.Sort(actions)
+	return actions
+}
+
+// Merge returns a copy of keys where the actions in overrides, given by mode
+// and action, are bound to their keys instead. Unknown modes and actions, and
+// keys bound to two actions of the same mode, are errors.
+func (keys KeyMap) Merge(overrides map[string]map[string][]string) (KeyMap, error) {
+	merged := KeyMap{}
+	for mode, bindings := range keys {
+		merged[mode] = slices.Clone(bindings)
+	}
+
+	for mode, actions := range overrides {
+		if _, ok := merged[mode]; !ok {
+			return nil, fmt.Errorf("unknown mode %q in keys, the modes are: %s", mode, strings.Join(Modes, ", "))
+		}
+		for action, actionKeys := range actions {
+			binding := merged.Binding(mode, action)
+			if binding == nil {
+				return nil, fmt.Errorf("unknown action %q in keys.%s, the actions are: %s", action, mode, strings.Join(keys.actions(mode), ", "))
+			}
+			if len(actionKeys) == 0 {
+				return nil, fmt.Errorf("keys.%s.%s has no keys", mode, action)
+			}
+			binding.SetKeys(slices.Clone(actionKeys)...)
+		}
+	}
+
+	for _, mode := range Modes {
+		bound := map[string]string{}
+		if mode == SwapMode || mode == MoveMode || mode == LayoutMode {
+			for _, action := range navigationActions {
+				for _, k := range merged.Binding(NormalMode, action).Keys() {
+					bound[k] = NormalMode + "." + action
+				}
+			}
+		}
+		for _, binding := range merged[mode] {
+			for _, k := range binding.Keys() {
+				if other, ok := bound[k]; ok {
+					return nil, fmt.Errorf("key %q is bound to both %s and %s.%s", keyName(k), other, mode, binding.Action)
+				}
+				bound[k] = mode + "." + binding.Action
+			}
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,157 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// PresetLayouts are tmux's builtin layouts, in the order they are cycled.
+var PresetLayouts = []string{"even-horizontal", "even-vertical", "main-horizontal", "main-vertical", "tiled"}
+
+// How many cells a pane grows or shrinks per key press.
+const resizeStep = 2
+
+// SavedLayout is a layout string kept under a name, for windows that are
+// arranged the same way often.
+type SavedLayout struct {
+	Name   string `yaml:"name"`
+	Layout string `yaml:"layout"`
+}
+
+func LayoutsPath() string {
+	return filepath.Join(DataDir(), "layouts.yaml")
+}
+
+func LoadLayouts() ([]SavedLayout, error) {
+	layouts := []SavedLayout{}
+
+	bytes, err := os.ReadFile(LayoutsPath())
+	if os.IsNotExist(err) {
+		return layouts, nil
+	} else if err != nil {
+		return layouts, err
+	}
+
+	if err := yaml.Unmarshal(bytes, &layouts); err != nil {
+		return layouts, fmt.Errorf("could not parse %s: %w", LayoutsPath(), err)
+	}
+
+	return layouts, nil
+}
+
+// SaveLayout stores layout under name, replacing any layout with that name.
+func SaveLayout(name string, layout string) error {
+	layouts, err := LoadLayouts()
+	if err != nil {
+		return err
+	}
+
+	layouts = slices.DeleteFunc(layouts, func(l SavedLayout) bool { return l.Name == name })
+	layouts = append(layouts, SavedLayout{name, layout})
+
+	bytes, err := yaml.Marshal(layouts)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(LayoutsPath()), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(LayoutsPath(), bytes, 0o644)
+}
+
+// layoutSize reads the size of the window out of a layout string like
+// "b25d,80x24,0,0,0".
+func layoutSize(layout string) (int, int) {
+	parts := strings.SplitN(layout, ",", 3)
+	if len(parts) < 2 {
+		return 0, 0
+	}
+	width, height := 0, 0
+	fmt.Sscanf(parts[1], "%dx%d", &width, &height)
+	return width, height
+}
+
+// layoutWindow is the window the layout editor works on: the one of the
+// selected pane.
+func (m AppModel) layoutWindow() *TmuxEntity {
+	if pane := m.panes.ItemWithId(m.panes.currentId); pane != nil {
+		return m.windows.ItemWithId(pane.parent)
+	}
+	return m.windows.ItemWithId(m.windows.currentId)
+}
+
+// LayoutPreview draws the panes of the layout window as boxes, scaled to the
+// preview, with the selected one highlighted.
+func (m AppModel) LayoutPreview() Frame {
+	frame := Frame{title: "Layout", focused: true}
+
+	window := m.layoutWindow()
+	if window == nil {
+		return frame
+	}
+	frame.title = fmt.Sprintf("Layout of %s", window.name)
+	if window.window.Zoomed {
+		frame.title += " (zoomed)"
+	}
+
+	width, height := m.previewSize()
+	if width < 2 || height < 2 {
+		return frame
+	}
+
+	c := newCanvas(width, height)
+	c.drawWindow(0, 0, width-1, height-1, *window, m.panes.items, m.panes.currentId, func(pane TmuxEntity, rows int) string {
+		return fmt.Sprintf("%%%d %s\n%dx%d", pane.id, pane.pane.CurrentCommand, pane.pane.Width, pane.pane.Height)
+	})
+
+	normalStyle := lipgloss.NewStyle().Foreground(m.theme.Foreground).Background(m.theme.Background)
+	frame.contents = c.render(normalStyle, normalStyle.Foreground(m.theme.Accent).Bold(true))
+
+	return frame
+}
+
+func resizePaneCmd(m AppModel, direction string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.client.ResizePane(paneTarget(m.panes.currentId), direction, resizeStep)
+		return resultMsg(err, refreshMsg{})
+	}
+}
+
+func toggleZoomCmd(m AppModel) tea.Cmd {
+	return func() tea.Msg {
+		err := m.client.ToggleZoom(paneTarget(m.panes.currentId))
+		return resultMsg(err, refreshMsg{})
+	}
+}
+
+func selectLayoutCmd(m AppModel, layout string) tea.Cmd {
+	window := m.layoutWindow()
+	return func() tea.Msg {
+		if window == nil {
+			return nil
+		}
+		err := m.client.SelectLayout(windowTarget(window.id), layout)
+		return resultMsg(err, refreshMsg{})
+	}
+}
+
+// saveLayoutCmd saves the layout of the layout window as name.
+func saveLayoutCmd(m AppModel, name string) tea.Cmd {
+	window := m.layoutWindow()
+	return func() tea.Msg {
+		if window == nil || len(name) == 0 {
+			return nil
+		}
+		err := SaveLayout(name, window.window.Layout)
+		return resultMsg(err, infoMsg(fmt.Sprintf("Saved layout %s", name)))
+	}
+}
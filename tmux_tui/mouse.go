@@ -0,0 +1,159 @@
+package tmux_tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Two clicks on the same item closer than this are a double click.
+const doubleClickInterval = 400 * time.Millisecond
+
+// Lines the preview scrolls per step of the wheel.
+const wheelLines = 3
+
+// click is the last item clicked, to tell double clicks apart.
+type click struct {
+	frame int
+	id    int
+	time  time.Time
+}
+
+// handleMouse focuses and selects what is clicked, goes to it on double
+// click, scrolls what is under the wheel and moves windows dropped on a
+// session.
+func (m *AppModel) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	layout := m.gridLayout()
+	frame := layout.frameAt(msg.X, msg.Y)
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp || msg.Button == tea.MouseButtonWheelDown:
+		return m.scroll(frame, msg.Button == tea.MouseButtonWheelDown)
+
+	case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft:
+		if frame == 0 {
+			return m.openScrollback()
+		}
+		if frame == -1 {
+			return nil
+		}
+
+		// Clicking a list leaves the preview
+		m.scrollback = nil
+		m.search = nil
+
+		clicked, item := m.entityAt(layout, frame, msg.Y)
+		if item == nil {
+			if frame != treeFrame {
+				m.focus(frame)
+			}
+			return m.refreshPreview()
+		}
+		if frame == treeFrame {
+			m.selectNode(treeNode{frame: clicked, entity: *item})
+		} else {
+			m.focus(frame)
+			m.list(frame).currentId = item.id
+		}
+		frame = clicked
+
+		now := time.Now()
+		if m.lastClick.frame == frame && m.lastClick.id == item.id && now.Sub(m.lastClick.time) < doubleClickInterval {
+			m.lastClick = click{}
+			return m.goToCmd(frame)
+		}
+		m.lastClick = click{frame, item.id, now}
+		if frame == 2 {
+			m.dragSrc = item.id
+		}
+		return listEntitiesCmd(*m)
+
+	case msg.Action == tea.MouseActionRelease:
+		src := m.dragSrc
+		m.dragSrc = -1
+		if src == -1 || frame == -1 || frame == 0 {
+			return nil
+		}
+		window := m.windows.ItemWithId(src)
+		dropped, session := m.entityAt(layout, frame, msg.Y)
+		if window == nil || session == nil || dropped != 1 || window.parent == session.id {
+			return nil
+		}
+		m.sessions.currentId = session.id
+		return moveWindowCmd(*m, []int{src}, false)
+	}
+
+	return nil
+}
+
+// entityAt is the item at row y of frame, and the list it belongs to.
+func (m AppModel) entityAt(layout gridLayout, frame int, y int) (int, *TmuxEntity) {
+	row := y - layout.list(frame).y - 1
+	if frame != treeFrame {
+		return frame, m.list(frame).ItemAt(row)
+	}
+	rows := m.treeRows()
+	index := treeOffset(m.treeCursor(rows), m.treeHeight()) + row
+	if index < 0 || index >= len(rows) {
+		return -1, nil
+	}
+	return rows[index].frame, &rows[index].entity
+}
+
+// scroll moves the selection of the list under the wheel, or the preview.
+func (m *AppModel) scroll(frame int, down bool) tea.Cmd {
+	delta := -1
+	if down {
+		delta = 1
+	}
+
+	switch frame {
+	case 0:
+		_, viewHeight := m.previewSize()
+		switch {
+		case m.search != nil:
+			m.search.Move(delta, viewHeight)
+		case m.scrollback != nil:
+			m.scrollback.Move(delta*wheelLines, viewHeight)
+			if m.scrollback.NearTop(viewHeight) {
+				return m.scrollback.olderCmd(*m)
+			}
+		case !down:
+			return m.openScrollback()
+		}
+	case treeFrame:
+		m.moveTreeCursor(delta)
+		return listEntitiesCmd(*m)
+	case 1, 2, 3:
+		m.list(frame).Move(delta)
+		return listEntitiesCmd(*m)
+	}
+
+	return nil
+}
+
+// openScrollback shows the history of the previewed pane, like the
+// scrollback action.
+func (m *AppModel) openScrollback() tea.Cmd {
+	if m.scrollback != nil || m.search != nil {
+		return nil
+	}
+	if pane := m.previewPane(); pane != nil {
+		m.scrollback = newScrollback(*pane)
+		return m.scrollback.firstPageCmd(*m)
+	}
+	return nil
+}
+
+// goToCmd switches to the selected item of frame.
+func (m AppModel) goToCmd(frame int) tea.Cmd {
+	switch frame {
+	case 1:
+		return goToSessionCmd(m)
+	case 2:
+		return goToWindowCmd(m)
+	case 3:
+		return goToPaneCmd(m)
+	}
+	return nil
+}
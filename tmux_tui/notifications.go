@@ -0,0 +1,75 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// How many errors are kept for the history overlay.
+const maxErrorHistory = 50
+
+type errorEntry struct {
+	time    time.Time
+	message string
+	count   int
+}
+
+// resultMsg returns msg if err is nil, otherwise an errorMsg for err.
+func resultMsg(err error, msg tea.Msg) tea.Msg {
+	if err != nil {
+		return errorMsg{err}
+	}
+	return msg
+}
+
+// pushError records an error in the history and shows it in the status bar.
+// Repeated errors, like the ones produced by every tick while tmux is down,
+// are collapsed into a single entry.
+func (m *AppModel) pushError(err error) {
+	message := strings.ReplaceAll(err.Error(), "\n", " ")
+	if len(m.errors) > 0 && m.errors[0].message == message {
+		m.errors[0].count++
+		m.errors[0].time = time.Now()
+	} else {
+		m.errors = append([]errorEntry{{time.Now(), message, 1}}, m.errors...)
+		if len(m.errors) > maxErrorHistory {
+			m.errors = m.errors[:maxErrorHistory]
+		}
+	}
+	m.notification = message
+	m.notificationIsError = true
+}
+
+// pushInfo shows a message in the status bar without recording it.
+func (m *AppModel) pushInfo(message string) {
+	m.notification = message
+	m.notificationIsError = false
+}
+
+func (m AppModel) ErrorHistory() Frame {
+	frame := NewFrame(m)
+	frame.title = "Errors"
+
+	normalStyle := lipgloss.NewStyle().Foreground(m.theme.Foreground).Background(m.theme.Background)
+	timeStyle := normalStyle.Foreground(m.theme.Secondary)
+
+	if len(m.errors) == 0 {
+		frame.contents = normalStyle.Render("No errors so far.")
+		return frame
+	}
+
+	lines := []string{}
+	for _, entry := range m.errors {
+		line := timeStyle.Render(entry.time.Format(time.TimeOnly)) + normalStyle.Render(" "+entry.message)
+		if entry.count > 1 {
+			line += normalStyle.Render(fmt.Sprintf(" (×%d)", entry.count))
+		}
+		lines = append(lines, line)
+	}
+	frame.contents = strings.Join(lines, "\n")
+	return frame
+}
@@ -0,0 +1,103 @@
+package tmux_tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigName is the template a project can keep at its root to
+// describe the session opened for it.
+const ProjectConfigName = ".tmux-tui.yaml"
+
+// OpenDirectory finds the session of the project in dir, the one that starts
+// in it or is named after it, or creates it from the project's template, if
+// it has one, or with a single window otherwise. It returns the session's id,
+// which is valid even when only ranking the directory up failed.
+func OpenDirectory(client TmuxClient, dir string) (int, error) {
+	dir, err := filepath.Abs(expandPath("", dir))
+	if err != nil {
+		return -1, err
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return -1, err
+	} else if !info.IsDir() {
+		return -1, fmt.Errorf("not a directory: %s", dir)
+	}
+
+	session, err := projectSession(client, dir)
+	if err != nil {
+		return -1, err
+	}
+	return session, VisitDirectory(dir)
+}
+
+// projectSession finds or creates the session of the project in dir.
+func projectSession(client TmuxClient, dir string) (int, error) {
+	template, hasTemplate, err := loadProjectTemplate(dir)
+	if err != nil {
+		return -1, err
+	}
+	if len(template.Name) == 0 {
+		template.Name = projectSessionName(dir)
+	}
+
+	entities, err := client.ListEntities()
+	if isNoServer(err) {
+		// No server running yet, so there is no session to find
+		entities = Entities{}
+	} else if err != nil {
+		return -1, err
+	}
+	for _, session := range entities.Sessions {
+		if session.session.Path == dir {
+			return session.id, nil
+		}
+	}
+	for _, session := range entities.Sessions {
+		if session.name == template.Name {
+			return session.id, nil
+		}
+	}
+
+	if !hasTemplate {
+		created, err := client.NewSession(NewSessionOptions{Name: template.Name, Directory: dir})
+		return created.Session, err
+	}
+	template.Root = expandPath(dir, template.Root)
+	return ApplyTemplate(client, template)
+}
+
+// loadProjectTemplate reads the template of the project in dir, if it has
+// one. Its name and root may be left out.
+func loadProjectTemplate(dir string) (Template, bool, error) {
+	template := Template{}
+	path := filepath.Join(dir, ProjectConfigName)
+
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return template, false, nil
+	} else if err != nil {
+		return template, false, err
+	}
+
+	if err := yaml.Unmarshal(bytes, &template); err != nil {
+		return template, false, fmt.Errorf("could not parse template %s: %w", path, err)
+	}
+	return template, true, nil
+}
+
+// openDirectoryCmd opens the session of the project in dir and goes to it.
+func openDirectoryCmd(m AppModel, dir string) tea.Cmd {
+	return func() tea.Msg {
+		session, err := OpenDirectory(m.client, dir)
+		if session != -1 {
+			err = errors.Join(m.client.SwitchClient(sessionTarget(session)), err)
+		}
+		return resultMsg(err, tea.QuitMsg{})
+	}
+}
@@ -0,0 +1,34 @@
+package tmux_tui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProjectSessionWithoutServer(t *testing.T) {
+	client := NewFakeClient()
+	client.Failures = map[string]error{"ListEntities": &TmuxError{
+		Args:     []string{"list-panes"},
+		Stderr:   "no server running on /tmp/tmux-1000/default",
+		ExitCode: 1,
+	}}
+
+	if _, err := projectSession(client, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.Entities.Sessions) != 1 {
+		t.Errorf("created %d sessions, expected one", len(client.Entities.Sessions))
+	}
+}
+
+func TestProjectSessionWhenListingFails(t *testing.T) {
+	client := NewFakeClient()
+	client.Failures = map[string]error{"ListEntities": errors.New("server exited unexpectedly")}
+
+	if _, err := projectSession(client, t.TempDir()); err == nil {
+		t.Error("listing failed but no error was returned")
+	}
+	if len(client.Entities.Sessions) != 0 {
+		t.Errorf("created %d sessions without knowing which exist", len(client.Entities.Sessions))
+	}
+}
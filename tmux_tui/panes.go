@@ -1,8 +1,7 @@
 package tmux_tui
 
 import (
-	"fmt"
-	"os/exec"
+	"errors"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -10,43 +9,69 @@ import (
 func goToPaneCmd(m AppModel) tea.Cmd {
 	return func() tea.Msg {
 		pane := m.panes.ItemWithId(m.panes.currentId)
+		if pane == nil {
+			return nil
+		}
 		window := m.windows.ItemWithId(pane.parent)
-		c := exec.Command("tmux",
-			"switch-client", "-t", fmt.Sprintf("$%d", window.parent), ";",
-			"select-window", "-t", fmt.Sprintf("@%d", window.id), ";",
-			"select-pane", "-t", fmt.Sprintf("%%%d", pane.id))
-		c.Run()
-		return tea.QuitMsg{}
+		if window == nil {
+			return nil
+		}
+		if err := m.client.SwitchClient(sessionTarget(window.parent)); err != nil {
+			return errorMsg{err}
+		}
+		if err := m.client.SelectWindow(windowTarget(window.id)); err != nil {
+			return errorMsg{err}
+		}
+		err := m.client.SelectPane(paneTarget(pane.id))
+		return resultMsg(err, tea.QuitMsg{})
 	}
 }
 
-func deletePaneCmd(m AppModel) tea.Cmd {
+func swapPanesCmd(m AppModel, src int) tea.Cmd {
 	return func() tea.Msg {
-		c := exec.Command("tmux", "kill-pane", "-t", fmt.Sprintf("%%%d", m.panes.currentId))
-		c.Run()
-		return tickMsg{}
+		err := m.client.SwapPanes(paneTarget(src), paneTarget(m.panes.currentId))
+		return resultMsg(err, refreshMsg{})
 	}
 }
 
-func swapPanesCmd(m AppModel, src int) tea.Cmd {
+func splitPane(m AppModel, horizontal bool) tea.Cmd {
 	return func() tea.Msg {
-		src := fmt.Sprintf("%%%d", src)
-		dst := fmt.Sprintf("%%%d", m.panes.currentId)
-		c := exec.Command("tmux", "swap-pane", "-s", src, "-t", dst)
-		c.Run()
-		return tickMsg{}
+		_, err := m.client.SplitPane(SplitPaneOptions{
+			Target:     paneTarget(m.panes.currentId),
+			Horizontal: horizontal,
+		})
+		return resultMsg(err, refreshMsg{})
 	}
 }
 
-func splitPane(m AppModel, horizontal bool) tea.Cmd {
+// joinPaneCmd moves pane src next to the selected pane, or into the selected
+// window when the windows frame has focus.
+func joinPaneCmd(m AppModel, srcs []int, horizontal bool) tea.Cmd {
+	return func() tea.Msg {
+		target := paneTarget(m.panes.currentId)
+		if m.focusedFrame == 2 {
+			target = windowTarget(m.windows.currentId)
+		}
+		errs := []error{}
+		for _, src := range srcs {
+			errs = append(errs, m.client.JoinPane(paneTarget(src), target, horizontal))
+		}
+		return resultMsg(errors.Join(errs...), refreshMsg{})
+	}
+}
+
+// breakPaneCmd moves the selected pane into a new window of its session.
+func breakPaneCmd(m AppModel) tea.Cmd {
 	return func() tea.Msg {
-		target := fmt.Sprintf("%%%d", m.panes.currentId)
-		orientation := "-v"
-		if horizontal {
-			orientation = "-h"
-		}
-		c := exec.Command("tmux", "split-pane", "-d", "-t", target, orientation)
-		c.Run()
-		return tickMsg{}
+		pane := m.panes.ItemWithId(m.panes.currentId)
+		if pane == nil {
+			return nil
+		}
+		window := m.windows.ItemWithId(pane.parent)
+		if window == nil {
+			return nil
+		}
+		_, err := m.client.BreakPane(paneTarget(pane.id), sessionTarget(window.parent))
+		return resultMsg(err, refreshMsg{})
 	}
 }
@@ -0,0 +1,61 @@
+package tmux_tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	PickNothing PickerAction = iota
+	PickTemplate
+	PickLayout
+)
+
+type (
+	PickerAction int
+
+	PickerItem struct {
+		label  string
+		detail string
+	}
+
+	// Picker is a list of choices drawn in place of the preview.
+	Picker struct {
+		title  string
+		items  []PickerItem
+		cursor int
+	}
+)
+
+func (picker *Picker) SelectNext() {
+	if picker.cursor+1 < len(picker.items) {
+		picker.cursor++
+	}
+}
+
+func (picker *Picker) SelectPrevious() {
+	if picker.cursor > 0 {
+		picker.cursor--
+	}
+}
+
+func (picker Picker) RenderContents(theme Theme) Frame {
+	itemStyle := lipgloss.NewStyle().Foreground(theme.Foreground).Background(theme.Background)
+	selectedStyle := itemStyle.Foreground(theme.Accent)
+	detailStyle := itemStyle.Foreground(theme.Secondary)
+
+	lines := []string{}
+	for i, item := range picker.items {
+		line := itemStyle.Render("  " + item.label)
+		if i == picker.cursor {
+			line = selectedStyle.Render("→ " + item.label)
+		}
+		if len(item.detail) > 0 {
+			line += itemStyle.Render(" ") + detailStyle.Render(item.detail)
+		}
+		lines = append(lines, line)
+	}
+
+	return Frame{title: picker.title, contents: strings.Join(lines, "\n"), focused: true}
+}
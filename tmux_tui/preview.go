@@ -0,0 +1,141 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// drawWindow draws the panes of window, picked from panes, at their relative
+// positions in the rectangle from left, top to right, bottom. text gives
+// what goes inside each pane, which has room for rows lines, and is only
+// asked for panes that show. The selected pane is highlighted.
+func (c *canvas) drawWindow(left int, top int, right int, bottom int, window TmuxEntity, panes []TmuxEntity, selected int, text func(pane TmuxEntity, rows int) string) {
+	windowWidth, windowHeight := layoutSize(window.window.Layout)
+	if windowWidth == 0 || windowHeight == 0 {
+		c.box(left, top, right, bottom, false)
+		return
+	}
+
+	// Borders sit between panes, so a pane's box goes from the cell before
+	// it to the cell after it. Shifted by one to start at zero.
+	x := func(cell int) int { return left + cell*(right-left)/(windowWidth+1) }
+	y := func(cell int) int { return top + cell*(bottom-top)/(windowHeight+1) }
+
+	for _, pane := range panes {
+		info := pane.pane
+		if pane.parent != window.id || window.window.Zoomed && !info.Active {
+			continue
+		}
+		boxLeft, boxRight := x(info.Left), x(info.Left+info.Width+1)
+		boxTop, boxBottom := y(info.Top), y(info.Top+info.Height+1)
+		c.box(boxLeft, boxTop, boxRight, boxBottom, pane.id == selected)
+		rows := boxBottom - boxTop - 1
+		if rows > 0 && boxRight-boxLeft > 1 {
+			c.writeBlock(boxTop+1, boxLeft+1, boxBottom, boxRight, text(pane, rows))
+		}
+	}
+}
+
+// drawSession draws thumbnails of the windows of session in a grid, with the
+// active one highlighted.
+func (c *canvas) drawSession(session int, windows []TmuxEntity, panes []TmuxEntity, text func(pane TmuxEntity, rows int) string) {
+	sessionWindows := []TmuxEntity{}
+	for _, window := range windows {
+		if window.parent == session {
+			sessionWindows = append(sessionWindows, window)
+		}
+	}
+	if len(sessionWindows) == 0 {
+		return
+	}
+
+	columns := int(math.Ceil(math.Sqrt(float64(len(sessionWindows)))))
+	rows := (len(sessionWindows) + columns - 1) / columns
+	cellWidth := (c.width - 1) / columns
+	cellHeight := (c.height - 1) / rows
+
+	for i, window := range sessionWindows {
+		left := (i % columns) * cellWidth
+		top := (i / columns) * cellHeight
+		right, bottom := left+cellWidth, top+cellHeight
+		c.drawWindow(left, top, right, bottom, window, panes, -1, text)
+		c.box(left, top, right, bottom, window.window.Active)
+		title := fmt.Sprintf(" [%d] %s ", window.window.Index, window.name)
+		c.write(top, left+1, right, title, window.window.Active)
+	}
+}
+
+// paneText captures the screen of each pane as it is drawn, so that hidden
+// panes are not captured at all. Colours are left out, as they don't survive
+// being cut into the boxes of a canvas.
+func (m AppModel) paneText(pane TmuxEntity, rows int) string {
+	content, err := m.client.CapturePane(paneTarget(pane.id), CaptureOptions{})
+	if err != nil {
+		return ""
+	}
+	return lastLines(content, rows)
+}
+
+// lastLines keeps the last n lines of text that are not blank, which is
+// where the prompt usually is.
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, " \n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m AppModel) renderCanvas(c *canvas) string {
+	normalStyle := lipgloss.NewStyle().Foreground(m.theme.Foreground).Background(m.theme.Background)
+	return c.render(normalStyle, normalStyle.Foreground(m.theme.Accent))
+}
+
+// windowPreview shows all panes of window id where they are on the screen,
+// with the active one highlighted.
+func (m AppModel) windowPreview(id int) string {
+	window := m.windows.ItemWithId(id)
+	width, height := m.previewSize()
+	if window == nil || width < 2 || height < 2 {
+		return ""
+	}
+
+	panes := []TmuxEntity{}
+	active := -1
+	for _, pane := range m.panes.items {
+		if pane.parent == id {
+			panes = append(panes, pane)
+			if pane.pane.Active {
+				active = pane.id
+			}
+		}
+	}
+	c := newCanvas(width, height)
+	c.drawWindow(0, 0, width-1, height-1, *window, panes, active, m.paneText)
+	return m.renderCanvas(c)
+}
+
+// sessionPreview shows a thumbnail of each window of session id.
+func (m AppModel) sessionPreview(id int) string {
+	width, height := m.previewSize()
+	if width < 2 || height < 2 {
+		return ""
+	}
+
+	windows := map[int]bool{}
+	for _, window := range m.windows.items {
+		windows[window.id] = window.parent == id
+	}
+	panes := []TmuxEntity{}
+	for _, pane := range m.panes.items {
+		if windows[pane.parent] {
+			panes = append(panes, pane)
+		}
+	}
+	c := newCanvas(width, height)
+	c.drawSession(id, m.windows.items, panes, m.paneText)
+	return m.renderCanvas(c)
+}
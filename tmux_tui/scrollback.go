@@ -0,0 +1,332 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+)
+
+// How many lines of history are fetched at a time.
+const scrollbackPage = 1000
+
+type (
+	// Scrollback is the history of a pane, shown in the preview when it has
+	// focus. It is fetched a page at a time, going back as the cursor does.
+	Scrollback struct {
+		pane   int
+		height int
+		lines  []string
+		// first is the tmux line number of lines[0], negative when it is in
+		// the history
+		first    int
+		complete bool
+		loading  bool
+
+		cursor int
+		offset int
+		// anchor is where the selection started, -1 when nothing is selected
+		anchor int
+
+		pattern *regexp.Regexp
+		matches []int
+	}
+
+	// scrollbackMsg carries the lines from start to end of pane, or why they
+	// could not be fetched.
+	scrollbackMsg struct {
+		pane  int
+		start string
+		lines []string
+		err   error
+	}
+)
+
+// previewPane is the pane shown by the preview: the selected one or the
+// active one of the selected window or session.
+func (m AppModel) previewPane() *TmuxEntity {
+	switch m.focusedFrame {
+	case 3:
+		return m.panes.ItemWithId(m.panes.currentId)
+	case 2:
+		return m.activePane(m.windows.currentId)
+	}
+	if window := m.activeWindow(m.sessions.currentId); window != nil {
+		return m.activePane(window.id)
+	}
+	return nil
+}
+
+func (m AppModel) activeWindow(session int) *TmuxEntity {
+	for i, window := range m.windows.items {
+		if window.parent == session && window.window.Active {
+			return &m.windows.items[i]
+		}
+	}
+	return nil
+}
+
+func (m AppModel) activePane(window int) *TmuxEntity {
+	for i, pane := range m.panes.items {
+		if pane.parent == window && pane.pane.Active {
+			return &m.panes.items[i]
+		}
+	}
+	return nil
+}
+
+func newScrollback(pane TmuxEntity) *Scrollback {
+	return &Scrollback{pane: pane.id, height: pane.pane.Height, anchor: -1, loading: true}
+}
+
+// loadScrollbackCmd fetches lines start to end of pane, where an empty end is
+// the bottom of the screen and a start of "-" the beginning of the history.
+func loadScrollbackCmd(m AppModel, pane int, start string, end string) tea.Cmd {
+	return func() tea.Msg {
+		contents, err := m.client.CapturePane(paneTarget(pane), CaptureOptions{Start: start, End: end})
+		if err != nil {
+			return scrollbackMsg{pane: pane, start: start, err: err}
+		}
+		lines := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+		return scrollbackMsg{pane: pane, start: start, lines: lines}
+	}
+}
+
+// firstPageCmd fetches the screen and the page of history above it.
+func (scrollback *Scrollback) firstPageCmd(m AppModel) tea.Cmd {
+	return loadScrollbackCmd(m, scrollback.pane, fmt.Sprint(-scrollbackPage), "")
+}
+
+// olderCmd fetches the page before the loaded lines, if there is one and it
+// is not being fetched already. It fetches the first page again if that one
+// failed.
+func (scrollback *Scrollback) olderCmd(m AppModel) tea.Cmd {
+	if scrollback.complete || scrollback.loading {
+		return nil
+	}
+	scrollback.loading = true
+	if len(scrollback.lines) == 0 {
+		return scrollback.firstPageCmd(m)
+	}
+	start := scrollback.first - scrollbackPage
+	return loadScrollbackCmd(m, scrollback.pane, fmt.Sprint(start), fmt.Sprint(scrollback.first-1))
+}
+
+// allCmd fetches the whole history, which searching needs.
+func (scrollback *Scrollback) allCmd(m AppModel) tea.Cmd {
+	if scrollback.complete {
+		return nil
+	}
+	scrollback.loading = true
+	return loadScrollbackCmd(m, scrollback.pane, "-", "")
+}
+
+func (scrollback *Scrollback) Load(msg scrollbackMsg, viewHeight int) {
+	scrollback.loading = false
+	if msg.err != nil {
+		// Moving the cursor up again fetches the page again
+		return
+	}
+	if scrollback.complete && msg.start != "-" {
+		// An older page that arrived after the whole history, which has it
+		return
+	}
+
+	switch {
+	case len(scrollback.lines) == 0:
+		scrollback.lines = msg.lines
+		scrollback.first = scrollback.height - len(msg.lines)
+		scrollback.complete = msg.start == "-" || scrollback.first > -scrollbackPage
+		scrollback.cursor = len(msg.lines) - 1
+	case msg.start == "-":
+		// Keeps the cursor on the same line, counting from the bottom
+		added := len(msg.lines) - len(scrollback.lines)
+		scrollback.lines = msg.lines
+		scrollback.first -= added
+		scrollback.complete = true
+		scrollback.shift(added)
+	default:
+		scrollback.lines = append(msg.lines, scrollback.lines...)
+		scrollback.first -= len(msg.lines)
+		scrollback.complete = len(msg.lines) < scrollbackPage
+		scrollback.shift(len(msg.lines))
+	}
+
+	scrollback.Search(scrollback.pattern)
+	scrollback.Move(0, viewHeight)
+}
+
+// shift moves every position down by n lines, after lines were added above.
+func (scrollback *Scrollback) shift(n int) {
+	scrollback.cursor += n
+	scrollback.offset += n
+	if scrollback.anchor != -1 {
+		scrollback.anchor += n
+	}
+}
+
+// Move moves the cursor by delta lines and scrolls to keep it visible in a
+// view of viewHeight lines.
+func (scrollback *Scrollback) Move(delta int, viewHeight int) {
+	scrollback.cursor = max(0, min(len(scrollback.lines)-1, scrollback.cursor+delta))
+	if scrollback.cursor < scrollback.offset {
+		scrollback.offset = scrollback.cursor
+	}
+	if scrollback.cursor >= scrollback.offset+viewHeight {
+		scrollback.offset = scrollback.cursor - viewHeight + 1
+	}
+	scrollback.offset = max(0, scrollback.offset)
+}
+
+// NearTop tells whether the cursor is close enough to the first loaded line
+// that the previous page should be fetched.
+func (scrollback *Scrollback) NearTop(viewHeight int) bool {
+	return scrollback.cursor < viewHeight
+}
+
+// Search finds the lines that match pattern. A nil pattern clears the search.
+func (scrollback *Scrollback) Search(pattern *regexp.Regexp) {
+	scrollback.pattern = pattern
+	scrollback.matches = nil
+	if pattern == nil {
+		return
+	}
+	for i, line := range scrollback.lines {
+		if pattern.MatchString(line) {
+			scrollback.matches = append(scrollback.matches, i)
+		}
+	}
+}
+
+// NextMatch moves the cursor to the next line that matches, going up when
+// backwards is set, and wraps around.
+func (scrollback *Scrollback) NextMatch(backwards bool, viewHeight int) bool {
+	if len(scrollback.matches) == 0 {
+		return false
+	}
+	target := -1
+	if backwards {
+		for i := len(scrollback.matches) - 1; i >= 0; i-- {
+			if scrollback.matches[i] < scrollback.cursor {
+				target = scrollback.matches[i]
+				break
+			}
+		}
+		if target == -1 {
+			target = scrollback.matches[len(scrollback.matches)-1]
+		}
+	} else {
+		index, _ := slices.BinarySearch(scrollback.matches, scrollback.cursor+1)
+		if index < len(scrollback.matches) {
+			target = scrollback.matches[index]
+		} else {
+			target = scrollback.matches[0]
+		}
+	}
+	scrollback.Move(target-scrollback.cursor, viewHeight)
+	return true
+}
+
+// ToggleSelection starts selecting at the cursor, or stops selecting.
+func (scrollback *Scrollback) ToggleSelection() {
+	if scrollback.anchor == -1 {
+		scrollback.anchor = scrollback.cursor
+	} else {
+		scrollback.anchor = -1
+	}
+}
+
+// Selection returns the selected lines, or the one under the cursor when
+// nothing is selected.
+func (scrollback *Scrollback) Selection() []string {
+	if len(scrollback.lines) == 0 {
+		return nil
+	}
+	start, end := scrollback.cursor, scrollback.cursor
+	if scrollback.anchor != -1 {
+		start, end = min(scrollback.anchor, scrollback.cursor), max(scrollback.anchor, scrollback.cursor)
+	}
+	return scrollback.lines[start : end+1]
+}
+
+func (scrollback *Scrollback) selected(line int) bool {
+	if scrollback.anchor == -1 {
+		return false
+	}
+	return line >= min(scrollback.anchor, scrollback.cursor) && line <= max(scrollback.anchor, scrollback.cursor)
+}
+
+// compileSearch compiles a search pattern, ignoring case unless it has
+// upper case letters.
+func compileSearch(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) == 0 {
+		return nil, nil
+	}
+	if strings.ToLower(pattern) == pattern {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func copySelectionCmd(m AppModel, lines []string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.client.SetBuffer(strings.Join(lines, "\n"))
+		if len(lines) == 1 {
+			return resultMsg(err, infoMsg("Copied 1 line to the paste buffer"))
+		}
+		return resultMsg(err, infoMsg(fmt.Sprintf("Copied %d lines to the paste buffer", len(lines))))
+	}
+}
+
+func (scrollback *Scrollback) RenderContents(theme Theme, width int, height int) Frame {
+	normalStyle := lipgloss.NewStyle().Foreground(theme.Foreground).Background(theme.Background)
+	matchStyle := normalStyle.Foreground(theme.Accent).Bold(true)
+	selectedStyle := normalStyle.Foreground(theme.Secondary)
+
+	frame := Frame{focused: true}
+	frame.title = fmt.Sprintf("[0] Scrollback (line %d of %d", scrollback.cursor+1, len(scrollback.lines))
+	if !scrollback.complete {
+		frame.title += "+"
+	}
+	frame.title += ")"
+	if scrollback.pattern != nil {
+		pattern := strings.TrimPrefix(scrollback.pattern.String(), "(?i)")
+		frame.title += fmt.Sprintf(" /%s/ %d matches", pattern, len(scrollback.matches))
+	}
+
+	rows := []string{}
+	end := min(len(scrollback.lines), scrollback.offset+height)
+	for i := scrollback.offset; i < end; i++ {
+		line := truncate.String(scrollback.lines[i], uint(width))
+
+		style := normalStyle
+		if scrollback.selected(i) {
+			style = selectedStyle
+		}
+		if i == scrollback.cursor {
+			style = style.Reverse(true)
+		}
+
+		positions := []int{}
+		if scrollback.pattern != nil {
+			positions = matchPositions(scrollback.pattern, line)
+		}
+
+		// Pads the cursor line so that it shows across the whole preview
+		if i == scrollback.cursor {
+			line += strings.Repeat(" ", max(0, width-utf8.RuneCountInString(line)))
+		}
+		rows = append(rows, highlight(line, positions, style, matchStyle))
+	}
+	if scrollback.loading && len(scrollback.lines) == 0 {
+		rows = append(rows, normalStyle.Render("Loading…"))
+	}
+
+	frame.contents = strings.Join(rows, "\n")
+	return frame
+}
@@ -0,0 +1,28 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"testing"
+)
+
+func numberedLines(from int, to int) []string {
+	lines := []string{}
+	for i := from; i < to; i++ {
+		lines = append(lines, fmt.Sprint(i))
+	}
+	return lines
+}
+
+func TestScrollbackDropsPagesAfterHistory(t *testing.T) {
+	scrollback := newScrollback(newPane(1, "zsh", 0, PaneInfo{Height: 10}))
+
+	// The last page and then the whole history, with 3000 lines before the screen
+	scrollback.Load(scrollbackMsg{1, fmt.Sprint(-scrollbackPage), numberedLines(2000, 3010), nil}, 10)
+	scrollback.Load(scrollbackMsg{1, "-", numberedLines(0, 3010), nil}, 10)
+	// The page before the last one, asked for before the whole history
+	scrollback.Load(scrollbackMsg{1, fmt.Sprint(-2 * scrollbackPage), numberedLines(1000, 2000), nil}, 10)
+
+	if len(scrollback.lines) != 3010 || scrollback.first != -3000 {
+		t.Errorf("has %d lines from %d, expected 3010 from -3000", len(scrollback.lines), scrollback.first)
+	}
+}
@@ -0,0 +1,199 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+)
+
+// More results than this are dropped, there is no point in scrolling through
+// them.
+const maxSearchResults = 1000
+
+type (
+	// GlobalSearch holds the lines of every pane that match a pattern.
+	GlobalSearch struct {
+		query    string
+		pattern  *regexp.Regexp
+		results  []SearchResult
+		contents map[int][]string
+		loading  bool
+		cursor   int
+		offset   int
+	}
+
+	SearchResult struct {
+		pane int
+		// path is session/window/pane
+		path string
+		line int
+	}
+
+	searchResultsMsg struct {
+		query    string
+		results  []SearchResult
+		contents map[int][]string
+	}
+)
+
+// searchPanesCmd captures every pane, with up to history lines of
+// scrollback (-1 for all of it), and looks for pattern in them.
+func searchPanesCmd(m AppModel, query string, pattern *regexp.Regexp, history int) tea.Cmd {
+	return func() tea.Msg {
+		entities, err := m.client.ListEntities()
+		if err != nil {
+			return errorMsg{err}
+		}
+		entities.linkPaths()
+		entities.sortWindows()
+
+		options := CaptureOptions{Start: "-"}
+		if history >= 0 {
+			options.Start = fmt.Sprint(-history)
+		}
+
+		// The pane tmux-tui runs in would only find the query being typed
+		own := os.Getenv("TMUX_PANE")
+
+		msg := searchResultsMsg{query: query, contents: map[int][]string{}}
+		for _, pane := range entities.Panes {
+			if paneTarget(pane.id) == own {
+				continue
+			}
+			contents, err := m.client.CapturePane(paneTarget(pane.id), options)
+			if err != nil {
+				continue
+			}
+			lines := strings.Split(strings.TrimRight(contents, "\n"), "\n")
+			path := fmt.Sprintf("%s/%%%d", strings.Join(pane.path, "/"), pane.id)
+			found := false
+			for i, line := range lines {
+				if len(msg.results) < maxSearchResults && pattern.MatchString(line) {
+					msg.results = append(msg.results, SearchResult{pane.id, path, i})
+					found = true
+				}
+			}
+			if found {
+				msg.contents[pane.id] = lines
+			}
+		}
+
+		return msg
+	}
+}
+
+func (search *GlobalSearch) Load(msg searchResultsMsg) {
+	search.loading = false
+	search.results = msg.results
+	search.contents = msg.contents
+	search.cursor = 0
+	search.offset = 0
+}
+
+func (search *GlobalSearch) Selected() *SearchResult {
+	if search.cursor < len(search.results) {
+		return &search.results[search.cursor]
+	}
+	return nil
+}
+
+func (search *GlobalSearch) Move(delta int, viewHeight int) {
+	search.cursor = max(0, min(len(search.results)-1, search.cursor+delta))
+	if search.cursor < search.offset {
+		search.offset = search.cursor
+	}
+	if search.cursor >= search.offset+viewHeight {
+		search.offset = search.cursor - viewHeight + 1
+	}
+}
+
+// matchPositions are the rune positions in line that pattern matches.
+func matchPositions(pattern *regexp.Regexp, line string) []int {
+	positions := []int{}
+	for _, match := range pattern.FindAllStringIndex(line, -1) {
+		start := utf8.RuneCountInString(line[:match[0]])
+		length := utf8.RuneCountInString(line[match[0]:match[1]])
+		for j := range length {
+			positions = append(positions, start+j)
+		}
+	}
+	return positions
+}
+
+// RenderContents lists the results on the left and the lines around the
+// selected one on the right.
+func (search *GlobalSearch) RenderContents(theme Theme, width int, height int) Frame {
+	normalStyle := lipgloss.NewStyle().Foreground(theme.Foreground).Background(theme.Background)
+	selectedStyle := normalStyle.Foreground(theme.Accent)
+	pathStyle := normalStyle.Foreground(theme.Secondary)
+	matchStyle := normalStyle.Foreground(theme.Accent).Bold(true)
+
+	frame := Frame{focused: true}
+	pattern := strings.TrimPrefix(search.pattern.String(), "(?i)")
+	switch {
+	case search.loading:
+		frame.title = fmt.Sprintf("Searching all panes for /%s/…", pattern)
+		return frame
+	case len(search.results) >= maxSearchResults:
+		frame.title = fmt.Sprintf("/%s/ in all panes: first %d matches", pattern, len(search.results))
+	default:
+		frame.title = fmt.Sprintf("/%s/ in all panes: %d matches", pattern, len(search.results))
+	}
+
+	if len(search.results) == 0 {
+		frame.contents = normalStyle.Render("Nothing found")
+		return frame
+	}
+
+	listWidth := width / 2
+	contextWidth := width - listWidth - 3
+
+	list := []string{}
+	end := min(len(search.results), search.offset+height)
+	for i := search.offset; i < end; i++ {
+		result := search.results[i]
+		prefix := "  "
+		if i == search.cursor {
+			prefix = "→ "
+		}
+		path := result.path + ": "
+		line := strings.TrimSpace(search.contents[result.pane][result.line])
+		room := max(0, listWidth-utf8.RuneCountInString(prefix+path))
+		line = truncate.String(line, uint(room))
+
+		style := normalStyle
+		if i == search.cursor {
+			style = selectedStyle
+		}
+		row := style.Render(prefix) + pathStyle.Render(path) + highlight(line, matchPositions(search.pattern, line), style, matchStyle)
+		list = append(list, normalStyle.Width(listWidth).Render(row))
+	}
+
+	context := []string{}
+	if result := search.Selected(); result != nil {
+		lines := search.contents[result.pane]
+		start := max(0, min(len(lines)-height, result.line-height/2))
+		for i := start; i < min(len(lines), start+height); i++ {
+			line := truncate.String(lines[i], uint(contextWidth))
+			style := normalStyle
+			if i == result.line {
+				style = style.Reverse(true)
+			}
+			context = append(context, highlight(line, matchPositions(search.pattern, line), style, matchStyle))
+		}
+	}
+
+	separator := strings.TrimSuffix(strings.Repeat(normalStyle.Foreground(theme.Secondary).Render("│")+"\n", height), "\n")
+	frame.contents = lipgloss.JoinHorizontal(lipgloss.Top,
+		strings.Join(list, "\n"),
+		normalStyle.Render(" "), separator, normalStyle.Render(" "),
+		strings.Join(context, "\n"))
+
+	return frame
+}
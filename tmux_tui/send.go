@@ -0,0 +1,93 @@
+package tmux_tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sendTargets are the panes input is sent to: the selected or marked panes,
+// or the active pane of the selected or marked windows and sessions.
+func (m AppModel) sendTargets() []int {
+	panes := []int{}
+	for _, id := range m.focusedList().Targets() {
+		var pane *TmuxEntity
+		switch m.focusedFrame {
+		case 1:
+			if window := m.activeWindow(id); window != nil {
+				pane = m.activePane(window.id)
+			}
+		case 2:
+			pane = m.activePane(id)
+		case 3:
+			pane = m.panes.ItemWithId(id)
+		}
+		if pane != nil {
+			panes = append(panes, pane.id)
+		}
+	}
+	return panes
+}
+
+func describePanes(panes []int) string {
+	if len(panes) == 1 {
+		return fmt.Sprintf("%%%d", panes[0])
+	}
+	return fmt.Sprintf("%d panes", len(panes))
+}
+
+// sendCmd types text into panes. A command is typed as is and followed by
+// Enter, otherwise text is a space separated list of tmux key names, like
+// "C-c Up Enter". A pane that fails doesn't stop the others from getting
+// the text, and the error tells which panes did.
+func sendCmd(m AppModel, panes []int, text string, command bool) tea.Cmd {
+	return func() tea.Msg {
+		if len(panes) == 0 || len(strings.TrimSpace(text)) == 0 {
+			return nil
+		}
+		sent := []string{}
+		errs := []error{}
+		for _, pane := range panes {
+			var err error
+			if command {
+				err = runInPane(m.client, pane, text)
+			} else {
+				err = m.client.SendKeys(paneTarget(pane), false, strings.Fields(text)...)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not send to %s: %w", paneTarget(pane), err))
+			} else {
+				sent = append(sent, paneTarget(pane))
+			}
+		}
+		if len(errs) == 0 {
+			return infoMsg(fmt.Sprintf("Sent to %s", describePanes(panes)))
+		}
+		if len(sent) > 0 {
+			errs = append([]error{fmt.Errorf("sent to %s", strings.Join(sent, ", "))}, errs...)
+		}
+		return errorMsg{errors.Join(errs...)}
+	}
+}
+
+// toggleSynchronizeCmd turns synchronize-panes on or off for the selected
+// window, or the window of the selected pane.
+func toggleSynchronizeCmd(m AppModel) tea.Cmd {
+	window := m.windows.ItemWithId(m.windows.currentId)
+	if m.focusedFrame == 3 {
+		window = m.layoutWindow()
+	}
+	return func() tea.Msg {
+		if window == nil {
+			return nil
+		}
+		value := "on"
+		if window.window.Synchronized {
+			value = "off"
+		}
+		err := m.client.SetWindowOption(windowTarget(window.id), "synchronize-panes", value)
+		return resultMsg(err, refreshMsg{})
+	}
+}
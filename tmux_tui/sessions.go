@@ -1,49 +1,81 @@
 package tmux_tui
 
 import (
+	"errors"
 	"fmt"
-	"os/exec"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func goToSessionCmd(m AppModel) tea.Cmd {
 	return func() tea.Msg {
-		c := exec.Command("tmux", "switch-client", "-t", fmt.Sprintf("$%d", m.sessions.currentId))
-		c.Run()
-		return tea.QuitMsg{}
+		if m.sessions.ItemWithId(m.sessions.currentId) == nil {
+			return nil
+		}
+		err := m.client.SwitchClient(sessionTarget(m.sessions.currentId))
+		return resultMsg(err, tea.QuitMsg{})
 	}
 }
 
 func renameSessionCmd(m AppModel) tea.Cmd {
 	return func() tea.Msg {
-		_ = goToSessionCmd(m)()
-		session := fmt.Sprintf("$%d", m.sessions.currentId)
-		c := exec.Command("tmux", "rename-session", "-t", session, m.textInput.Value())
-		c.Run()
-		return clearInputTextMsg{}
+		session := sessionTarget(m.sessions.currentId)
+		if err := m.client.SwitchClient(session); err != nil {
+			return errorMsg{err}
+		}
+		err := m.client.RenameSession(session, m.textInput.Value())
+		return resultMsg(err, clearInputTextMsg{})
+	}
+}
+
+// newSessionCmd creates a session named name that starts in directory, and
+// switches to it if it has a name. Without a name, a session that starts in a
+// directory is the project's session, which is opened like OpenDirectory
+// does.
+func newSessionCmd(m AppModel, name string, directory string) tea.Cmd {
+	return func() tea.Msg {
+		if len(name) == 0 && len(directory) > 0 {
+			session, err := OpenDirectory(m.client, directory)
+			if session != -1 {
+				err = errors.Join(m.client.SwitchClient(sessionTarget(session)), err)
+			}
+			return resultMsg(err, clearInputTextMsg{})
+		}
+
+		created, err := m.client.NewSession(NewSessionOptions{Name: name, Directory: directory})
+		if err != nil {
+			return errorMsg{err}
+		}
+		if len(name) > 0 {
+			err = m.client.SwitchClient(sessionTarget(created.Session))
+		}
+		if len(directory) > 0 {
+			err = errors.Join(err, VisitDirectory(directory))
+		}
+		return resultMsg(err, clearInputTextMsg{})
 	}
 }
 
-func newSessionCmd(m AppModel) tea.Cmd {
+func applyTemplateCmd(m AppModel, template Template) tea.Cmd {
 	return func() tea.Msg {
-		if len(m.textInput.Value()) > 0 {
-			c := exec.Command("tmux",
-				"new-session", "-ds", m.textInput.Value(), ";",
-				"switch-client", "-t", m.textInput.Value())
-			c.Run()
-		} else {
-			c := exec.Command("tmux", "new-session", "-d")
-			c.Run()
-		}
-		return clearInputTextMsg{}
+		session, err := ApplyTemplate(m.client, template)
+		if err == nil {
+			err = m.client.SwitchClient(sessionTarget(session))
+		}
+		return resultMsg(err, refreshMsg{})
 	}
 }
 
-func deleteSessionCmd(m AppModel) tea.Cmd {
+func saveSnapshotCmd(m AppModel) tea.Cmd {
 	return func() tea.Msg {
-		c := exec.Command("tmux", "kill-session", "-t", m.sessions.ItemWithId(m.sessions.currentId).name)
-		c.Run()
-		return tickMsg{}
+		snapshot, err := TakeSnapshot(m.client, 0)
+		if err != nil {
+			return errorMsg{err}
+		}
+		path := DefaultSnapshotPath()
+		if err := SaveSnapshot(path, snapshot); err != nil {
+			return errorMsg{err}
+		}
+		return infoMsg(fmt.Sprintf("Snapshot saved to %s", path))
 	}
 }
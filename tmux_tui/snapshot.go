@@ -0,0 +1,399 @@
+package tmux_tui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SnapshotVersion is bumped whenever the snapshot format changes in a way
+// older versions of tmux-tui can't read.
+const SnapshotVersion = 1
+
+// Snapshot is the state of a whole tmux server, as saved by `tmux-tui save`.
+type Snapshot struct {
+	Version  int               `yaml:"version" json:"version"`
+	Created  time.Time         `yaml:"created" json:"created"`
+	Sessions []SessionSnapshot `yaml:"sessions" json:"sessions"`
+}
+
+type SessionSnapshot struct {
+	Name    string           `yaml:"name" json:"name"`
+	Windows []WindowSnapshot `yaml:"windows" json:"windows"`
+}
+
+type WindowSnapshot struct {
+	Name   string         `yaml:"name" json:"name"`
+	Index  int            `yaml:"index" json:"index"`
+	Layout string         `yaml:"layout" json:"layout"`
+	Active bool           `yaml:"active" json:"active"`
+	Panes  []PaneSnapshot `yaml:"panes" json:"panes"`
+}
+
+type PaneSnapshot struct {
+	Path string `yaml:"path" json:"path"`
+	// Command is the whole command line, with its arguments
+	Command    string `yaml:"command" json:"command"`
+	Active     bool   `yaml:"active" json:"active"`
+	Scrollback string `yaml:"scrollback,omitempty" json:"scrollback,omitempty"`
+}
+
+// DataDir is where tmux-tui keeps the files it writes, following the XDG
+// base directory specification.
+func DataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); len(dir) > 0 {
+		return filepath.Join(dir, "tmux-tui")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "tmux-tui")
+}
+
+func DefaultSnapshotPath() string {
+	return filepath.Join(DataDir(), "snapshot.yaml")
+}
+
+// TakeSnapshot records every session, window and pane. When history is not
+// zero the contents of each pane are saved too, including up to history lines
+// of scrollback (-1 for all of it).
+func TakeSnapshot(client TmuxClient, history int) (Snapshot, error) {
+	snapshot := Snapshot{Version: SnapshotVersion, Created: time.Now()}
+
+	entities, err := client.ListEntities()
+	if err != nil {
+		return snapshot, err
+	}
+
+	commands, err := paneCommands(client, entities.Panes)
+	if err != nil {
+		return snapshot, err
+	}
+
+	for _, session := range entities.Sessions {
+		sessionSnapshot, err := snapshotSession(client, entities, commands, session, history)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.Sessions = append(snapshot.Sessions, sessionSnapshot)
+	}
+
+	return snapshot, nil
+}
+
+func snapshotSession(client TmuxClient, entities Entities, commands map[int]string, session TmuxEntity, history int) (SessionSnapshot, error) {
+	sessionSnapshot := SessionSnapshot{Name: session.name}
+
+	windows := slices.DeleteFunc(slices.Clone(entities.Windows), func(w TmuxEntity) bool {
+		return w.parent != session.id
+	})
+	slices.SortStableFunc(windows, func(a, b TmuxEntity) int {
+		return a.window.Index - b.window.Index
+	})
+
+	for _, window := range windows {
+		windowSnapshot, err := snapshotWindow(client, entities, commands, window, history)
+		if err != nil {
+			return sessionSnapshot, err
+		}
+		sessionSnapshot.Windows = append(sessionSnapshot.Windows, windowSnapshot)
+	}
+
+	return sessionSnapshot, nil
+}
+
+func snapshotWindow(client TmuxClient, entities Entities, commands map[int]string, window TmuxEntity, history int) (WindowSnapshot, error) {
+	windowSnapshot := WindowSnapshot{
+		Name:   window.name,
+		Index:  window.window.Index,
+		Layout: window.window.Layout,
+		Active: window.window.Active,
+	}
+
+	for _, pane := range entities.Panes {
+		if pane.parent != window.id {
+			continue
+		}
+		paneSnapshot, err := snapshotPane(client, commands, pane, history)
+		if err != nil {
+			return windowSnapshot, err
+		}
+		windowSnapshot.Panes = append(windowSnapshot.Panes, paneSnapshot)
+	}
+
+	return windowSnapshot, nil
+}
+
+// paneCommands finds the command lines running in panes, by PID, as
+// snapshots need them.
+func paneCommands(client TmuxClient, panes []TmuxEntity) (map[int]string, error) {
+	pids := []int{}
+	for _, pane := range panes {
+		pids = append(pids, pane.pane.PID)
+	}
+	return client.CommandLines(pids)
+}
+
+func snapshotPane(client TmuxClient, commands map[int]string, pane TmuxEntity, history int) (PaneSnapshot, error) {
+	paneSnapshot := PaneSnapshot{
+		Path:    pane.pane.CurrentPath,
+		Command: commands[pane.pane.PID],
+		Active:  pane.pane.Active,
+	}
+	// The process may have exited since tmux was asked
+	if len(paneSnapshot.Command) == 0 {
+		paneSnapshot.Command = pane.pane.CurrentCommand
+	}
+
+	if history != 0 {
+		options := CaptureOptions{Escapes: true, Start: "-"}
+		if history > 0 {
+			options.Start = fmt.Sprint(-history)
+		}
+		var err error
+		paneSnapshot.Scrollback, err = client.CapturePane(paneTarget(pane.id), options)
+		if err != nil {
+			return paneSnapshot, err
+		}
+	}
+
+	return paneSnapshot, nil
+}
+
+// SaveSnapshot writes snapshot to path, as JSON if its extension is .json and
+// as YAML otherwise.
+func SaveSnapshot(path string, snapshot Snapshot) error {
+	var bytes []byte
+	var err error
+	if filepath.Ext(path) == ".json" {
+		bytes, err = json.MarshalIndent(snapshot, "", "  ")
+	} else {
+		bytes, err = yaml.Marshal(snapshot)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, bytes, 0o600)
+}
+
+func LoadSnapshot(path string) (Snapshot, error) {
+	snapshot := Snapshot{}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(bytes, &snapshot)
+	} else {
+		err = yaml.Unmarshal(bytes, &snapshot)
+	}
+	if err != nil {
+		return snapshot, fmt.Errorf("could not parse snapshot %s: %w", path, err)
+	}
+
+	if snapshot.Version > SnapshotVersion {
+		return snapshot, fmt.Errorf("snapshot %s was written by a newer version of tmux-tui (format %d)", path, snapshot.Version)
+	}
+
+	return snapshot, nil
+}
+
+// RestoreSnapshot recreates the sessions in snapshot. Sessions that already
+// exist are left alone and their names returned. Commands that are not shells
+// are typed again into their panes, and saved scrollback is printed before the
+// shell starts.
+func RestoreSnapshot(client TmuxClient, snapshot Snapshot) ([]string, error) {
+	entities, err := client.ListEntities()
+	if isNoServer(err) {
+		// No server running yet, which is the usual case after a reboot
+		entities = Entities{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	skipped := []string{}
+	errs := []error{}
+	for _, session := range snapshot.Sessions {
+		exists := slices.ContainsFunc(entities.Sessions, func(s TmuxEntity) bool {
+			return s.name == session.Name
+		})
+		if exists {
+			skipped = append(skipped, session.Name)
+			continue
+		}
+		if err := restoreSession(client, session); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", session.Name, err))
+		}
+	}
+
+	return skipped, errors.Join(errs...)
+}
+
+func restoreSession(client TmuxClient, session SessionSnapshot) error {
+	session_id := -1
+	activeWindow := -1
+	for _, window := range session.Windows {
+		created, err := restoreWindow(client, session_id, session.Name, window)
+		if err != nil {
+			return err
+		}
+		session_id = created.Session
+		if window.Active {
+			activeWindow = created.Window
+		}
+	}
+
+	if activeWindow != -1 {
+		return client.SelectWindow(windowTarget(activeWindow))
+	}
+
+	return nil
+}
+
+// restoreWindow recreates window, at its index, in session or, when session
+// is -1, in a new session called sessionName.
+func restoreWindow(client TmuxClient, session int, sessionName string, window WindowSnapshot) (Created, error) {
+	panes := window.Panes
+	if len(panes) == 0 {
+		panes = []PaneSnapshot{{}}
+	}
+
+	command, err := scrollbackCommand(panes[0])
+	if err != nil {
+		return Created{}, err
+	}
+
+	var created Created
+	if session == -1 {
+		created, err = client.NewSession(NewSessionOptions{
+			Name:       sessionName,
+			Directory:  panes[0].Path,
+			WindowName: window.Name,
+			Command:    command,
+		})
+		// The first window starts at the base index, so it is moved to its
+		// own before the others take theirs
+		if err == nil {
+			err = placeWindow(client, created, window.Index)
+		}
+	} else {
+		created, err = client.NewWindow(NewWindowOptions{
+			Session:   sessionTarget(session),
+			Index:     strconv.Itoa(window.Index),
+			Name:      window.Name,
+			Directory: panes[0].Path,
+			Detached:  true,
+			Command:   command,
+		})
+	}
+	if err != nil {
+		return created, err
+	}
+
+	createdPanes := []int{created.Pane}
+	for _, pane := range panes[1:] {
+		split, err := restorePane(client, createdPanes[len(createdPanes)-1], pane)
+		if err != nil {
+			return created, err
+		}
+		// Keeps room for the next split
+		if err := client.SelectLayout(windowTarget(created.Window), "tiled"); err != nil {
+			return created, err
+		}
+		createdPanes = append(createdPanes, split)
+	}
+
+	if len(window.Layout) > 0 {
+		if err := client.SelectLayout(windowTarget(created.Window), window.Layout); err != nil {
+			return created, err
+		}
+	}
+
+	for i, pane := range panes {
+		if pane.Active {
+			if err := client.SelectPane(paneTarget(createdPanes[i])); err != nil {
+				return created, err
+			}
+		}
+		if isShell(pane.Command) {
+			continue
+		}
+		if err := runInPane(client, createdPanes[i], pane.Command); err != nil {
+			return created, err
+		}
+	}
+
+	return created, nil
+}
+
+// restorePane splits neighbour to recreate pane, without starting its
+// command, and returns the new pane's id.
+func restorePane(client TmuxClient, neighbour int, pane PaneSnapshot) (int, error) {
+	command, err := scrollbackCommand(pane)
+	if err != nil {
+		return -1, err
+	}
+	split, err := client.SplitPane(SplitPaneOptions{
+		Target:    paneTarget(neighbour),
+		Directory: pane.Path,
+		Command:   command,
+	})
+	return split.Pane, err
+}
+
+// scrollbackCommand is the command that starts a restored pane: it prints
+// the saved scrollback, if any, and then starts a shell.
+func scrollbackCommand(pane PaneSnapshot) (string, error) {
+	if len(pane.Scrollback) == 0 {
+		return "", nil
+	}
+	file, err := os.CreateTemp("", "tmux-tui-scrollback-*")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(strings.TrimRight(pane.Scrollback, "\n") + "\n"); err != nil {
+		return "", err
+	}
+	// The pane deletes the file once it has printed it
+	return fmt.Sprintf(`cat '%s'; rm -f '%s'; exec "${SHELL:-sh}"`, file.Name(), file.Name()), nil
+}
+
+// isShell tells whether command is an interactive shell, which needs not be
+// started again in a restored pane. Only the program is looked at, so that
+// "zsh" and "/bin/zsh -l" are both shells.
+func isShell(command string) bool {
+	program, _, _ := strings.Cut(strings.TrimSpace(command), " ")
+	switch filepath.Base(strings.TrimPrefix(program, "-")) {
+	case "", "sh", "bash", "zsh", "fish", "dash", "ksh", "mksh", "tcsh", "csh", "nu", "xonsh", "elvish", "pwsh":
+		return true
+	}
+	return false
+}
+
+// placeWindow moves the window that was just created to index in its
+// session, unless it is already there.
+func placeWindow(client TmuxClient, created Created, index int) error {
+	entities, err := client.ListEntities()
+	if err != nil {
+		return err
+	}
+	window := slices.IndexFunc(entities.Windows, func(w TmuxEntity) bool { return w.id == created.Window })
+	if window == -1 || entities.Windows[window].window.Index == index {
+		return nil
+	}
+	return client.MoveWindow(windowTarget(created.Window), fmt.Sprintf("%s:%d", sessionTarget(created.Session), index), false)
+}
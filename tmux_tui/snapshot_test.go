@@ -0,0 +1,76 @@
+package tmux_tui
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRestoreWindowIndices(t *testing.T) {
+	client := NewFakeClient()
+	snapshot := Snapshot{Sessions: []SessionSnapshot{{
+		Name: "work",
+		Windows: []WindowSnapshot{
+			{Name: "editor", Index: 1},
+			{Name: "shell", Index: 0},
+			{Name: "logs", Index: 4, Active: true},
+		},
+	}}}
+
+	if _, err := RestoreSnapshot(client, snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	indices := []int{}
+	for _, window := range client.Entities.Windows {
+		indices = append(indices, window.window.Index)
+	}
+	if !slices.Equal(indices, []int{1, 0, 4}) {
+		t.Errorf("restored windows at %v, expected %v", indices, []int{1, 0, 4})
+	}
+}
+
+func TestSnapshotCommandLines(t *testing.T) {
+	client := NewFakeClient()
+	_, window, _ := client.AddSession("work")
+	client.AddPane(window, "vim")
+	for i := range client.Entities.Panes {
+		client.Entities.Panes[i].pane.PID = 1000 + i
+	}
+	client.Entities.Panes[1].pane.Active = true
+	client.Foreground = map[int]string{1000: "-zsh", 1001: "vim notes.txt"}
+
+	snapshot, err := TakeSnapshot(client, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	panes := snapshot.Sessions[0].Windows[0].Panes
+	if panes[0].Command != "-zsh" || panes[0].Active || panes[1].Command != "vim notes.txt" || !panes[1].Active {
+		t.Fatalf("saved panes %+v, expected the shell and then the active editor with its arguments", panes)
+	}
+
+	restored := NewFakeClient()
+	if _, err := RestoreSnapshot(restored, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	pane := restored.Entities.Panes[1].id
+	if !slices.Contains(restored.Calls, "SendKeys "+paneTarget(pane)+" true vim notes.txt") {
+		t.Errorf("restoring typed %v, expected the editor's whole command line", restored.Calls)
+	}
+	if restored.Entities.CurrentPane != pane {
+		t.Errorf("pane %d is active after restoring, expected %d", restored.Entities.CurrentPane, pane)
+	}
+}
+
+func TestRestoreWhenListingFails(t *testing.T) {
+	client := NewFakeClient()
+	client.Failures = map[string]error{"ListEntities": errors.New("server exited unexpectedly")}
+	snapshot := Snapshot{Sessions: []SessionSnapshot{{Name: "work", Windows: []WindowSnapshot{{Name: "shell"}}}}}
+
+	if _, err := RestoreSnapshot(client, snapshot); err == nil {
+		t.Error("listing failed but no error was returned")
+	}
+	if len(client.Entities.Sessions) != 0 {
+		t.Errorf("restored %d sessions without knowing which exist", len(client.Entities.Sessions))
+	}
+}
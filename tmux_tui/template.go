@@ -0,0 +1,245 @@
+package tmux_tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Template describes a session to be created: its windows, how they are
+// split and what runs in each pane.
+type Template struct {
+	Name        string
+	Root        string
+	Environment map[string]string
+	Windows     []WindowTemplate
+}
+
+type WindowTemplate struct {
+	Name string
+	// Root is relative to the template's root, unless absolute.
+	Root string
+	// Layout is one of tmux's preset layouts or a layout string.
+	Layout string
+	Panes  []PaneTemplate
+}
+
+type PaneTemplate struct {
+	// Root is relative to the window's root, unless absolute.
+	Root    string
+	Command string
+	// Split is either "horizontal" or "vertical" (the default). It is
+	// ignored for the first pane, which is created along with the window.
+	Split string
+	Size  string
+}
+
+// ConfigDir is where tmux-tui looks for its files, following the XDG base
+// directory specification.
+func ConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); len(dir) > 0 {
+		return filepath.Join(dir, "tmux-tui")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "tmux-tui")
+}
+
+// TemplatesDir is where templates are looked up by name.
+func TemplatesDir() string {
+	return filepath.Join(ConfigDir(), "templates")
+}
+
+func LoadTemplate(path string) (Template, error) {
+	template := Template{}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return template, err
+	}
+
+	if err := yaml.Unmarshal(bytes, &template); err != nil {
+		return template, fmt.Errorf("could not parse template %s: %w", path, err)
+	}
+
+	if err := template.Validate(); err != nil {
+		return template, fmt.Errorf("invalid template %s: %w", path, err)
+	}
+
+	return template, nil
+}
+
+// FindTemplate loads the template at path or, if there's no such file, the
+// one with that name in TemplatesDir.
+func FindTemplate(path string) (Template, error) {
+	if _, err := os.Stat(path); err == nil || strings.ContainsRune(path, os.PathSeparator) {
+		return LoadTemplate(path)
+	}
+	for _, extension := range []string{".yaml", ".yml"} {
+		candidate := filepath.Join(TemplatesDir(), path+extension)
+		if _, err := os.Stat(candidate); err == nil {
+			return LoadTemplate(candidate)
+		}
+	}
+	return Template{}, fmt.Errorf("template not found: %s", path)
+}
+
+// ListTemplates loads every template in TemplatesDir. Templates that can't
+// be loaded are reported in the error, the others are still returned.
+func ListTemplates() ([]Template, error) {
+	entries, err := os.ReadDir(TemplatesDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	templates := []Template{}
+	errs := []error{}
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if entry.IsDir() || (extension != ".yaml" && extension != ".yml") {
+			continue
+		}
+		template, err := LoadTemplate(filepath.Join(TemplatesDir(), entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		templates = append(templates, template)
+	}
+
+	slices.SortFunc(templates, func(a, b Template) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return templates, errors.Join(errs...)
+}
+
+func (template Template) Validate() error {
+	if len(template.Name) == 0 {
+		return errors.New("the session needs a name")
+	}
+	for i, window := range template.Windows {
+		for j, pane := range window.Panes {
+			if pane.Split != "" && pane.Split != "horizontal" && pane.Split != "vertical" {
+				return fmt.Errorf("window %d, pane %d: split must be horizontal or vertical, not %q", i+1, j+1, pane.Split)
+			}
+		}
+	}
+	return nil
+}
+
+// expandPath resolves ~ and makes path relative to root.
+func expandPath(root string, path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if len(path) == 0 {
+		return root
+	}
+	if filepath.IsAbs(path) || len(root) == 0 {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// ApplyTemplate creates the session described by template and returns its
+// id. If something fails halfway, the partially created session is left
+// behind so the error can be inspected.
+func ApplyTemplate(client TmuxClient, template Template) (int, error) {
+	if err := template.Validate(); err != nil {
+		return -1, err
+	}
+
+	root := expandPath("", template.Root)
+	windows := template.Windows
+	if len(windows) == 0 {
+		windows = []WindowTemplate{{}}
+	}
+
+	session := -1
+	for i, window := range windows {
+		directory := expandPath(root, window.Root)
+		panes := window.Panes
+		if len(panes) == 0 {
+			panes = []PaneTemplate{{}}
+		}
+
+		var created Created
+		var err error
+		if i == 0 {
+			created, err = client.NewSession(NewSessionOptions{
+				Name:        template.Name,
+				Directory:   expandPath(directory, panes[0].Root),
+				WindowName:  window.Name,
+				Environment: template.Environment,
+			})
+		} else {
+			created, err = client.NewWindow(NewWindowOptions{
+				Session:   sessionTarget(session),
+				Name:      window.Name,
+				Directory: expandPath(directory, panes[0].Root),
+				Detached:  true,
+			})
+		}
+		if err != nil {
+			return session, err
+		}
+		session = created.Session
+
+		pane := created.Pane
+		if err := runInPane(client, pane, panes[0].Command); err != nil {
+			return session, err
+		}
+
+		for _, paneTemplate := range panes[1:] {
+			split, err := client.SplitPane(SplitPaneOptions{
+				Target:     paneTarget(pane),
+				Horizontal: paneTemplate.Split == "horizontal",
+				Directory:  expandPath(directory, paneTemplate.Root),
+				Size:       paneTemplate.Size,
+			})
+			if err != nil {
+				return session, err
+			}
+			pane = split.Pane
+
+			// Keeps room for the next split
+			if len(window.Layout) > 0 {
+				if err := client.SelectLayout(windowTarget(created.Window), window.Layout); err != nil {
+					return session, err
+				}
+			}
+
+			if err := runInPane(client, pane, paneTemplate.Command); err != nil {
+				return session, err
+			}
+		}
+
+		if len(window.Layout) > 0 {
+			if err := client.SelectLayout(windowTarget(created.Window), window.Layout); err != nil {
+				return session, err
+			}
+		}
+	}
+
+	return session, nil
+}
+
+// runInPane types command into the pane's shell, so that the shell is still
+// there once the command exits.
+func runInPane(client TmuxClient, pane int, command string) error {
+	if len(command) == 0 {
+		return nil
+	}
+	if err := client.SendKeys(paneTarget(pane), true, command); err != nil {
+		return err
+	}
+	return client.SendKeys(paneTarget(pane), false, "Enter")
+}
@@ -0,0 +1,602 @@
+package tmux_tui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TmuxClient is the set of tmux operations the TUI performs. Targets use
+// tmux's own syntax ($session, @window, %pane or a name).
+type TmuxClient interface {
+	ListEntities() (Entities, error)
+	CapturePane(target string, options CaptureOptions) (string, error)
+
+	SwitchClient(target string) error
+	SelectWindow(target string) error
+	SelectPane(target string) error
+
+	NewSession(options NewSessionOptions) (Created, error)
+	NewWindow(options NewWindowOptions) (Created, error)
+	SplitPane(options SplitPaneOptions) (Created, error)
+	SelectLayout(target string, layout string) error
+	// ResizePane moves the border of pane target on the given side ("L",
+	// "R", "U" or "D") by cells.
+	ResizePane(target string, direction string, cells int) error
+	ToggleZoom(target string) error
+	// SendKeys types keys into a pane. Unless literal is set, key names
+	// like Enter or C-c are translated.
+	SendKeys(target string, literal bool, keys ...string) error
+	// SetBuffer puts data in the paste buffer.
+	SetBuffer(data string) error
+	SetWindowOption(target string, name string, value string) error
+
+	RenameSession(target string, name string) error
+	RenameWindow(target string, name string) error
+
+	KillSession(target string) error
+	KillWindow(target string) error
+	KillPane(target string) error
+
+	SwapWindows(src string, dst string) error
+	SwapPanes(src string, dst string) error
+
+	// RenumberWindows closes the gaps between the window indices of session.
+	RenumberWindows(session string) error
+	// MoveWindow moves window src to the end of session dst or, with link,
+	// shows it in dst too. dst can be written as session:index to put the
+	// window at that index instead.
+	MoveWindow(src string, dst string, link bool) error
+	// JoinPane moves pane src next to dst, which may be a window or a pane.
+	JoinPane(src string, dst string, horizontal bool) error
+	// BreakPane moves pane src into a new window of session dst.
+	BreakPane(src string, dst string) (Created, error)
+
+	// DescendantCounts tells how many processes descend from each of pids,
+	// which is how busy a pane is.
+	DescendantCounts(pids []int) (map[int]int, error)
+	// CommandLines tells the full command line of the process in the
+	// foreground of each of pids, which is what a pane is running.
+	CommandLines(pids []int) (map[int]string, error)
+
+	// Subscribe opens a stream of change notifications. Callers fall back to
+	// polling ListEntities when it fails.
+	Subscribe() (Subscription, error)
+}
+
+// Entities is a snapshot of every session, window and pane on the server,
+// along with the ones the client is currently looking at.
+type Entities struct {
+	Sessions       []TmuxEntity
+	Windows        []TmuxEntity
+	Panes          []TmuxEntity
+	CurrentSession int
+	CurrentWindow  int
+	CurrentPane    int
+}
+
+type CaptureOptions struct {
+	// Escapes keeps colours and text attributes.
+	Escapes bool
+	// Start and End are the first and last lines captured. Negative numbers
+	// reach into the history and "-" means its beginning (Start) or the end
+	// of the screen (End). Empty means the visible screen.
+	Start string
+	End   string
+}
+
+type NewSessionOptions struct {
+	// Name is chosen by tmux when empty.
+	Name        string
+	Directory   string
+	WindowName  string
+	Environment map[string]string
+	// Command runs instead of the default shell.
+	Command string
+}
+
+type NewWindowOptions struct {
+	Session string
+	// Index is where the window goes in the session, after the last window
+	// when empty.
+	Index     string
+	Name      string
+	Directory string
+	// Detached keeps the session's current window selected.
+	Detached bool
+	Command  string
+}
+
+type SplitPaneOptions struct {
+	Target     string
+	Horizontal bool
+	Directory  string
+	// Size of the new pane, in cells or as a percentage like "30%".
+	Size    string
+	Command string
+}
+
+// Created holds the ids of what a new-session, new-window or split-window
+// created, along with the ids of the entities that contain it.
+type Created struct {
+	Session int
+	Window  int
+	Pane    int
+}
+
+func sessionTarget(id int) string {
+	return fmt.Sprintf("$%d", id)
+}
+
+func windowTarget(id int) string {
+	return fmt.Sprintf("@%d", id)
+}
+
+func paneTarget(id int) string {
+	return fmt.Sprintf("%%%d", id)
+}
+
+// ExecClient talks to tmux by running the tmux binary.
+type ExecClient struct {
+	// Socket is passed to tmux with -S when not empty.
+	Socket string
+	// Client is the client switch-client acts on. When empty, tmux picks one.
+	Client string
+}
+
+func NewExecClient() *ExecClient {
+	return &ExecClient{}
+}
+
+func (client *ExecClient) command(args ...string) *exec.Cmd {
+	if len(client.Socket) > 0 {
+		args = append([]string{"-S", client.Socket}, args...)
+	}
+	return exec.Command("tmux", args...)
+}
+
+// TmuxError is returned when tmux exits with an error. It carries what tmux
+// printed to stderr, which is usually the only useful explanation.
+type TmuxError struct {
+	Args     []string
+	Stderr   string
+	ExitCode int
+}
+
+func (err *TmuxError) Error() string {
+	command := "tmux"
+	if len(err.Args) > 0 {
+		command = err.Args[0]
+	}
+	if len(err.Stderr) > 0 {
+		return fmt.Sprintf("%s: %s", command, err.Stderr)
+	}
+	return fmt.Sprintf("%s: exit status %d", command, err.ExitCode)
+}
+
+// isNoServer tells whether err is tmux saying that no server is running,
+// rather than a server failing to answer.
+func isNoServer(err error) bool {
+	var tmuxErr *TmuxError
+	if !errors.As(err, &tmuxErr) {
+		return false
+	}
+	// Depending on the version, a missing socket is reported either way
+	stderr := tmuxErr.Stderr
+	return strings.HasPrefix(stderr, "no server running") ||
+		strings.HasPrefix(stderr, "error connecting to") && strings.Contains(stderr, "No such file or directory")
+}
+
+func (client *ExecClient) run(args ...string) error {
+	_, err := client.output(args...)
+	return err
+}
+
+func (client *ExecClient) output(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	c := client.command(args...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), &TmuxError{
+			Args:     args,
+			Stderr:   strings.TrimSpace(stderr.String()),
+			ExitCode: exitErr.ExitCode(),
+		}
+	}
+	return stdout.String(), err
+}
+
+// Fields fetched for every pane by ListEntities, in order. Each line of
+// output is tagged so panes, clients and the current position can be told
+// apart.
+var entityFormat = strings.Join([]string{
+	"P",
+	"#{session_id}", "#{window_id}", "#{pane_id}",
+	"#{session_name}", "#{session_created}", "#{session_activity}", "#{session_group}",
+	"#{window_name}", "#{window_index}", "#{window_layout}", "#{window_active}",
+	"#{window_zoomed_flag}", "#{window_bell_flag}", "#{window_activity_flag}", "#{window_silence_flag}",
+	"#{pane_current_command}", "#{pane_current_path}", "#{pane_title}", "#{pane_pid}",
+	"#{pane_width}", "#{pane_height}", "#{pane_dead}", "#{pane_in_mode}",
+	"#{pane_left}", "#{pane_top}", "#{pane_active}", "#{pane_synchronized}",
+	"#{session_path}", "#{window_linked}",
+}, "\t")
+
+func (client *ExecClient) ListEntities() (Entities, error) {
+	// Fetches info about all sessions, windows and panes at once
+	output, err := client.output(
+		"list-panes", "-aF", entityFormat, ";",
+		"list-clients", "-F", "L\t#{session_id}\t#{client_control_mode}", ";",
+		"display-message", "-p", "C\t#{session_id}\t#{window_id}\t#{pane_id}")
+	if err != nil {
+		return Entities{}, err
+	}
+	return parseEntities(output), nil
+}
+
+func parseEntities(output string) Entities {
+	entities := Entities{}
+	attached := map[int]int{}
+
+	id := func(s string, prefix string) int {
+		id, err := strconv.Atoi(strings.TrimPrefix(s, prefix))
+		if err != nil {
+			return -1
+		}
+		return id
+	}
+	number := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	timestamp := func(s string) time.Time {
+		return time.Unix(int64(number(s)), 0)
+	}
+	flag := func(s string) bool {
+		return s == "1"
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "\t")
+
+		switch {
+		case parts[0] == "C" && len(parts) == 4:
+			entities.CurrentSession = id(parts[1], "$")
+			entities.CurrentWindow = id(parts[2], "@")
+			entities.CurrentPane = id(parts[3], "%")
+		case parts[0] == "L" && len(parts) == 3:
+			if parts[2] != "1" {
+				attached[id(parts[1], "$")]++
+			}
+		case parts[0] == "P" && len(parts) == 30:
+			session_id := id(parts[1], "$")
+			window_id := id(parts[2], "@")
+			pane_id := id(parts[3], "%")
+			if session_id == -1 || window_id == -1 || pane_id == -1 {
+				continue
+			}
+
+			entities.Sessions = append(entities.Sessions, newSession(session_id, parts[4], SessionInfo{
+				Created:  timestamp(parts[5]),
+				Activity: timestamp(parts[6]),
+				Group:    parts[7],
+				Path:     parts[28],
+			}))
+			entities.Windows = append(entities.Windows, newWindow(window_id, parts[8], session_id, WindowInfo{
+				Index:    number(parts[9]),
+				Layout:   parts[10],
+				Active:   flag(parts[11]),
+				Zoomed:   flag(parts[12]),
+				Bell:     flag(parts[13]),
+				Activity: flag(parts[14]),
+				Silence:  flag(parts[15]),
+				// Set per window, but only available per pane
+				Synchronized: flag(parts[27]),
+				Linked:       flag(parts[29]),
+			}))
+			entities.Panes = append(entities.Panes, newPane(pane_id, parts[16], window_id, PaneInfo{
+				CurrentCommand: parts[16],
+				CurrentPath:    parts[17],
+				Title:          parts[18],
+				PID:            number(parts[19]),
+				Width:          number(parts[20]),
+				Height:         number(parts[21]),
+				Dead:           flag(parts[22]),
+				InMode:         flag(parts[23]),
+				Left:           number(parts[24]),
+				Top:            number(parts[25]),
+				Active:         flag(parts[26]),
+			}))
+		}
+	}
+
+	// Every pane is listed once per window it is in and every window once
+	// per session. Linked windows, and their panes, are only kept in the
+	// first session they are in, and are badged as linked.
+	unique := func(entities []TmuxEntity) []TmuxEntity {
+		seen := map[int]bool{}
+		return slices.DeleteFunc(entities, func(entity TmuxEntity) bool {
+			duplicate := seen[entity.id]
+			seen[entity.id] = true
+			return duplicate
+		})
+	}
+
+	entities.Sessions = unique(entities.Sessions)
+	entities.Windows = unique(entities.Windows)
+	entities.Panes = unique(entities.Panes)
+
+	for _, session := range entities.Sessions {
+		session.session.Attached = attached[session.id]
+	}
+
+	return entities
+}
+
+func (client *ExecClient) CapturePane(target string, options CaptureOptions) (string, error) {
+	args := []string{"capture-pane", "-p", "-t", target}
+	if options.Escapes {
+		args = append(args, "-e")
+	}
+	if len(options.Start) > 0 {
+		args = append(args, "-S", options.Start)
+	}
+	if len(options.End) > 0 {
+		args = append(args, "-E", options.End)
+	}
+	return client.output(args...)
+}
+
+func (client *ExecClient) SwitchClient(target string) error {
+	if len(client.Client) > 0 {
+		return client.run("switch-client", "-c", client.Client, "-t", target)
+	}
+	return client.run("switch-client", "-t", target)
+}
+
+func (client *ExecClient) SelectWindow(target string) error {
+	return client.run("select-window", "-t", target)
+}
+
+func (client *ExecClient) SelectPane(target string) error {
+	return client.run("select-pane", "-t", target)
+}
+
+// Printed by commands that create entities, parsed by created.
+const createdFormat = "#{session_id}\t#{window_id}\t#{pane_id}"
+
+func created(output string, err error) (Created, error) {
+	if err != nil {
+		return Created{}, err
+	}
+	parts := strings.Split(strings.TrimSpace(output), "\t")
+	if len(parts) != 3 {
+		return Created{}, fmt.Errorf("unexpected output from tmux: %q", output)
+	}
+	ids := [3]int{}
+	for i, prefix := range []string{"$", "@", "%"} {
+		ids[i], err = strconv.Atoi(strings.TrimPrefix(parts[i], prefix))
+		if err != nil {
+			return Created{}, fmt.Errorf("unexpected output from tmux: %q", output)
+		}
+	}
+	return Created{ids[0], ids[1], ids[2]}, nil
+}
+
+func (client *ExecClient) NewSession(options NewSessionOptions) (Created, error) {
+	args := []string{"new-session", "-d", "-P", "-F", createdFormat}
+	if len(options.Name) > 0 {
+		args = append(args, "-s", options.Name)
+	}
+	if len(options.Directory) > 0 {
+		args = append(args, "-c", options.Directory)
+	}
+	if len(options.WindowName) > 0 {
+		args = append(args, "-n", options.WindowName)
+	}
+	for _, key := range slices.Sorted(maps.Keys(options.Environment)) {
+		args = append(args, "-e", key+"="+options.Environment[key])
+	}
+	if len(options.Command) > 0 {
+		args = append(args, options.Command)
+	}
+	return created(client.output(args...))
+}
+
+func (client *ExecClient) NewWindow(options NewWindowOptions) (Created, error) {
+	args := []string{"new-window", "-P", "-F", createdFormat, "-t", options.Session + ":" + options.Index}
+	if options.Detached {
+		args = append(args, "-d")
+	}
+	if len(options.Name) > 0 {
+		args = append(args, "-n", options.Name)
+	}
+	if len(options.Directory) > 0 {
+		args = append(args, "-c", options.Directory)
+	}
+	if len(options.Command) > 0 {
+		args = append(args, options.Command)
+	}
+	return created(client.output(args...))
+}
+
+func (client *ExecClient) SplitPane(options SplitPaneOptions) (Created, error) {
+	args := []string{"split-window", "-d", "-P", "-F", createdFormat, "-t", options.Target}
+	if options.Horizontal {
+		args = append(args, "-h")
+	} else {
+		args = append(args, "-v")
+	}
+	if len(options.Directory) > 0 {
+		args = append(args, "-c", options.Directory)
+	}
+	if len(options.Size) > 0 {
+		args = append(args, "-l", options.Size)
+	}
+	if len(options.Command) > 0 {
+		args = append(args, options.Command)
+	}
+	return created(client.output(args...))
+}
+
+func (client *ExecClient) SelectLayout(target string, layout string) error {
+	return client.run("select-layout", "-t", target, layout)
+}
+
+func (client *ExecClient) ResizePane(target string, direction string, cells int) error {
+	return client.run("resize-pane", "-t", target, "-"+direction, strconv.Itoa(cells))
+}
+
+func (client *ExecClient) ToggleZoom(target string) error {
+	return client.run("resize-pane", "-Z", "-t", target)
+}
+
+func (client *ExecClient) SendKeys(target string, literal bool, keys ...string) error {
+	args := []string{"send-keys", "-t", target}
+	if literal {
+		args = append(args, "-l")
+	}
+	return client.run(append(args, keys...)...)
+}
+
+func (client *ExecClient) SetBuffer(data string) error {
+	return client.run("set-buffer", "--", data)
+}
+
+func (client *ExecClient) SetWindowOption(target string, name string, value string) error {
+	return client.run("set-option", "-w", "-t", target, name, value)
+}
+
+func (client *ExecClient) RenameSession(target string, name string) error {
+	return client.run("rename-session", "-t", target, name)
+}
+
+func (client *ExecClient) RenameWindow(target string, name string) error {
+	return client.run("rename-window", "-t", target, name)
+}
+
+func (client *ExecClient) KillSession(target string) error {
+	return client.run("kill-session", "-t", target)
+}
+
+func (client *ExecClient) KillWindow(target string) error {
+	return client.run("kill-window", "-t", target)
+}
+
+func (client *ExecClient) KillPane(target string) error {
+	return client.run("kill-pane", "-t", target)
+}
+
+func (client *ExecClient) SwapWindows(src string, dst string) error {
+	return client.run("swap-window", "-s", src, "-t", dst)
+}
+
+func (client *ExecClient) SwapPanes(src string, dst string) error {
+	return client.run("swap-pane", "-s", src, "-t", dst)
+}
+
+func (client *ExecClient) RenumberWindows(session string) error {
+	return client.run("move-window", "-r", "-t", session)
+}
+
+func (client *ExecClient) MoveWindow(src string, dst string, link bool) error {
+	if !strings.Contains(dst, ":") {
+		dst += ":"
+	}
+	if link {
+		return client.run("link-window", "-d", "-s", src, "-t", dst)
+	}
+	return client.run("move-window", "-d", "-s", src, "-t", dst)
+}
+
+func (client *ExecClient) JoinPane(src string, dst string, horizontal bool) error {
+	direction := "-v"
+	if horizontal {
+		direction = "-h"
+	}
+	return client.run("join-pane", "-d", direction, "-s", src, "-t", dst)
+}
+
+func (client *ExecClient) BreakPane(src string, dst string) (Created, error) {
+	return created(client.output("break-pane", "-d", "-P", "-F", createdFormat, "-s", src, "-t", dst+":"))
+}
+
+func (client *ExecClient) DescendantCounts(pids []int) (map[int]int, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[int][]int{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 == nil && err2 == nil {
+			children[ppid] = append(children[ppid], pid)
+		}
+	}
+
+	var count func(pid int) int
+	count = func(pid int) int {
+		total := 0
+		for _, child := range children[pid] {
+			total += 1 + count(child)
+		}
+		return total
+	}
+
+	counts := map[int]int{}
+	for _, pid := range pids {
+		counts[pid] = count(pid)
+	}
+	return counts, nil
+}
+
+func (client *ExecClient) CommandLines(pids []int) (map[int]string, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "tpgid=", "-o", "args=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// The foreground process group of each process's terminal, and the
+	// command line of each process
+	foreground := map[int]int{}
+	args := map[int]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		pidField, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+		groupField, command, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		pid, err1 := strconv.Atoi(pidField)
+		group, err2 := strconv.Atoi(groupField)
+		if err1 == nil && err2 == nil {
+			foreground[pid] = group
+			args[pid] = strings.TrimSpace(command)
+		}
+	}
+
+	commands := map[int]string{}
+	for _, pid := range pids {
+		// The group's leader is the command that was typed, or the first
+		// one of a pipeline
+		if command, ok := args[foreground[pid]]; ok {
+			commands[pid] = command
+		} else if command, ok := args[pid]; ok {
+			commands[pid] = command
+		}
+	}
+	return commands, nil
+}
@@ -0,0 +1,647 @@
+package tmux_tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FakeClient is an in-memory TmuxClient. It keeps a tree of sessions,
+// windows and panes and mutates it the way tmux would, so the TUI can be
+// exercised without a running server.
+type FakeClient struct {
+	Entities Entities
+	// Contents maps pane ids to what CapturePane returns for them.
+	Contents map[int]string
+	// Calls records every method invoked, e.g. "KillPane %3".
+	Calls []string
+	// Failures makes the calls written like in Calls fail with an error.
+	Failures map[string]error
+	// Buffer is the paste buffer.
+	Buffer string
+	// Descendants maps pane PIDs to how many processes run under them.
+	Descendants map[int]int
+	// Foreground maps pane PIDs to the command line running in them.
+	Foreground map[int]string
+
+	nextId int
+}
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{Contents: map[int]string{}, nextId: 100}
+}
+
+// AddSession creates a session with a single window and pane and returns
+// the ids of the three.
+func (client *FakeClient) AddSession(name string) (int, int, int) {
+	session := client.newId()
+	client.Entities.Sessions = append(client.Entities.Sessions, newSession(session, name, SessionInfo{Created: time.Now(), Activity: time.Now()}))
+	window, pane := client.AddWindow(session, "shell")
+	return session, window, pane
+}
+
+// AddWindow creates a window with a single pane in session and returns the
+// ids of both.
+func (client *FakeClient) AddWindow(session int, name string) (int, int) {
+	window := client.newId()
+	index := 0
+	for _, w := range client.Entities.Windows {
+		if w.parent == session {
+			index = max(index, w.window.Index+1)
+		}
+	}
+	client.Entities.Windows = append(client.Entities.Windows, newWindow(window, name, session, WindowInfo{Index: index, Layout: "b25d,80x24,0,0,0"}))
+	pane := client.AddPane(window, "zsh")
+	return window, pane
+}
+
+// AddPane creates a pane in window and returns its id.
+func (client *FakeClient) AddPane(window int, command string) int {
+	pane := client.newId()
+	client.Entities.Panes = append(client.Entities.Panes, newPane(pane, command, window, PaneInfo{CurrentCommand: command, Width: 80, Height: 24}))
+	return pane
+}
+
+func (client *FakeClient) newId() int {
+	client.nextId++
+	return client.nextId
+}
+
+// record adds a call to Calls and returns the error it should fail with.
+func (client *FakeClient) record(method string, args ...string) error {
+	call := strings.TrimSpace(method + " " + strings.Join(args, " "))
+	client.Calls = append(client.Calls, call)
+	return client.Failures[call]
+}
+
+func findEntity(entities []TmuxEntity, prefix string, target string) int {
+	if strings.HasPrefix(target, prefix) {
+		id, err := strconv.Atoi(strings.TrimPrefix(target, prefix))
+		if err == nil {
+			return slices.IndexFunc(entities, func(e TmuxEntity) bool { return e.id == id })
+		}
+	}
+	return slices.IndexFunc(entities, func(e TmuxEntity) bool { return e.name == target })
+}
+
+func (client *FakeClient) session(target string) (int, error) {
+	target = strings.TrimSuffix(target, ":")
+	index := findEntity(client.Entities.Sessions, "$", target)
+	if index == -1 {
+		return -1, fmt.Errorf("can't find session: %s", target)
+	}
+	return index, nil
+}
+
+func (client *FakeClient) window(target string) (int, error) {
+	index := findEntity(client.Entities.Windows, "@", target)
+	if index == -1 {
+		return -1, fmt.Errorf("can't find window: %s", target)
+	}
+	return index, nil
+}
+
+func (client *FakeClient) pane(target string) (int, error) {
+	index := findEntity(client.Entities.Panes, "%", target)
+	if index == -1 {
+		return -1, fmt.Errorf("can't find pane: %s", target)
+	}
+	return index, nil
+}
+
+func (client *FakeClient) ListEntities() (Entities, error) {
+	if err := client.record("ListEntities"); err != nil {
+		return Entities{}, err
+	}
+	entities := client.Entities
+	entities.Sessions = copyEntities(entities.Sessions)
+	entities.Windows = copyEntities(entities.Windows)
+	entities.Panes = copyEntities(entities.Panes)
+	return entities, nil
+}
+
+// copyEntities copies entities along with their info, so that callers
+// cannot change the fake's state through them.
+func copyEntities(entities []TmuxEntity) []TmuxEntity {
+	copies := slices.Clone(entities)
+	for i, entity := range copies {
+		copies[i].path = slices.Clone(entity.path)
+		if entity.session != nil {
+			session := *entity.session
+			copies[i].session = &session
+		}
+		if entity.window != nil {
+			window := *entity.window
+			copies[i].window = &window
+		}
+		if entity.pane != nil {
+			pane := *entity.pane
+			copies[i].pane = &pane
+		}
+	}
+	return copies
+}
+
+func (client *FakeClient) CapturePane(target string, options CaptureOptions) (string, error) {
+	if err := client.record("CapturePane", target, options.Start, options.End); err != nil {
+		return "", err
+	}
+	if index, err := client.pane(target); err == nil {
+		return client.Contents[client.Entities.Panes[index].id], nil
+	}
+	if _, err := client.window(target); err == nil {
+		return "", nil
+	}
+	if _, err := client.session(target); err == nil {
+		return "", nil
+	}
+	return "", fmt.Errorf("can't find pane: %s", target)
+}
+
+func (client *FakeClient) SwitchClient(target string) error {
+	if err := client.record("SwitchClient", target); err != nil {
+		return err
+	}
+	index, err := client.session(target)
+	if err != nil {
+		return err
+	}
+	session := client.Entities.Sessions[index].id
+	client.Entities.CurrentSession = session
+	// Lands on the first window, where tmux would go to the session's current one
+	window := slices.IndexFunc(client.Entities.Windows, func(w TmuxEntity) bool { return w.parent == session })
+	if window != -1 {
+		client.enterWindow(client.Entities.Windows[window].id)
+	}
+	return nil
+}
+
+func (client *FakeClient) SelectWindow(target string) error {
+	if err := client.record("SelectWindow", target); err != nil {
+		return err
+	}
+	index, err := client.window(target)
+	if err != nil {
+		return err
+	}
+	client.enterWindow(client.Entities.Windows[index].id)
+	return nil
+}
+
+// enterWindow makes window and its first pane the current ones.
+func (client *FakeClient) enterWindow(window int) {
+	client.Entities.CurrentWindow = window
+	pane := slices.IndexFunc(client.Entities.Panes, func(p TmuxEntity) bool { return p.parent == window })
+	if pane != -1 {
+		client.Entities.CurrentPane = client.Entities.Panes[pane].id
+	}
+}
+
+func (client *FakeClient) SelectPane(target string) error {
+	if err := client.record("SelectPane", target); err != nil {
+		return err
+	}
+	index, err := client.pane(target)
+	if err != nil {
+		return err
+	}
+	client.Entities.CurrentPane = client.Entities.Panes[index].id
+	return nil
+}
+
+func (client *FakeClient) NewSession(options NewSessionOptions) (Created, error) {
+	if err := client.record("NewSession", options.Name, options.Directory); err != nil {
+		return Created{}, err
+	}
+	name := options.Name
+	if len(name) == 0 {
+		name = strconv.Itoa(len(client.Entities.Sessions))
+	} else if _, err := client.session(name); err == nil {
+		return Created{}, fmt.Errorf("duplicate session: %s", name)
+	}
+	session, window, pane := client.AddSession(name)
+	if len(options.WindowName) > 0 {
+		client.Entities.Windows[len(client.Entities.Windows)-1].name = options.WindowName
+	}
+	client.setPath(pane, options.Directory)
+	client.Entities.Sessions[len(client.Entities.Sessions)-1].session.Path = options.Directory
+	return Created{session, window, pane}, nil
+}
+
+func (client *FakeClient) NewWindow(options NewWindowOptions) (Created, error) {
+	if err := client.record("NewWindow", options.Session, options.Index, options.Name, options.Directory); err != nil {
+		return Created{}, err
+	}
+	index, err := client.session(options.Session)
+	if err != nil {
+		return Created{}, err
+	}
+	session := client.Entities.Sessions[index].id
+	if len(options.Index) > 0 && client.indexInUse(session, options.Index) {
+		return Created{}, fmt.Errorf("index in use: %s", options.Index)
+	}
+	window, pane := client.AddWindow(session, options.Name)
+	if len(options.Index) > 0 {
+		client.Entities.Windows[len(client.Entities.Windows)-1].window.Index, _ = strconv.Atoi(options.Index)
+	}
+	client.setPath(pane, options.Directory)
+	return Created{session, window, pane}, nil
+}
+
+func (client *FakeClient) SplitPane(options SplitPaneOptions) (Created, error) {
+	if err := client.record("SplitPane", options.Target, strconv.FormatBool(options.Horizontal), options.Directory); err != nil {
+		return Created{}, err
+	}
+	index, err := client.pane(options.Target)
+	if err != nil {
+		return Created{}, err
+	}
+	window := client.Entities.Panes[index].parent
+	pane := client.AddPane(window, "zsh")
+	client.setPath(pane, options.Directory)
+	session := client.Entities.Windows[findEntity(client.Entities.Windows, "@", windowTarget(window))].parent
+	return Created{session, window, pane}, nil
+}
+
+func (client *FakeClient) setPath(pane int, path string) {
+	index := findEntity(client.Entities.Panes, "%", paneTarget(pane))
+	client.Entities.Panes[index].pane.CurrentPath = path
+}
+
+func (client *FakeClient) ResizePane(target string, direction string, cells int) error {
+	if err := client.record("ResizePane", target, direction, strconv.Itoa(cells)); err != nil {
+		return err
+	}
+	index, err := client.pane(target)
+	if err != nil {
+		return err
+	}
+	// As if the pane were the top left one
+	pane := client.Entities.Panes[index].pane
+	switch direction {
+	case "L":
+		pane.Width -= cells
+	case "R":
+		pane.Width += cells
+	case "U":
+		pane.Height -= cells
+	case "D":
+		pane.Height += cells
+	}
+	return nil
+}
+
+func (client *FakeClient) ToggleZoom(target string) error {
+	if err := client.record("ToggleZoom", target); err != nil {
+		return err
+	}
+	index, err := client.pane(target)
+	if err != nil {
+		return err
+	}
+	window, err := client.window(windowTarget(client.Entities.Panes[index].parent))
+	if err != nil {
+		return err
+	}
+	info := client.Entities.Windows[window].window
+	info.Zoomed = !info.Zoomed
+	return nil
+}
+
+func (client *FakeClient) SelectLayout(target string, layout string) error {
+	if err := client.record("SelectLayout", target, layout); err != nil {
+		return err
+	}
+	index, err := client.window(target)
+	if err != nil {
+		return err
+	}
+	client.Entities.Windows[index].window.Layout = layout
+	return nil
+}
+
+func (client *FakeClient) SendKeys(target string, literal bool, keys ...string) error {
+	if err := client.record("SendKeys", append([]string{target, strconv.FormatBool(literal)}, keys...)...); err != nil {
+		return err
+	}
+	index, err := client.pane(target)
+	if err != nil {
+		return err
+	}
+	id := client.Entities.Panes[index].id
+	for _, key := range keys {
+		if literal {
+			client.Contents[id] += key
+		} else if key == "Enter" {
+			client.Contents[id] += "\n"
+		}
+	}
+	return nil
+}
+
+func (client *FakeClient) SetBuffer(data string) error {
+	if err := client.record("SetBuffer"); err != nil {
+		return err
+	}
+	client.Buffer = data
+	return nil
+}
+
+func (client *FakeClient) SetWindowOption(target string, name string, value string) error {
+	if err := client.record("SetWindowOption", target, name, value); err != nil {
+		return err
+	}
+	index, err := client.window(target)
+	if err != nil {
+		return err
+	}
+	if name == "synchronize-panes" {
+		client.Entities.Windows[index].window.Synchronized = value == "on"
+	}
+	return nil
+}
+
+func (client *FakeClient) RenameSession(target string, name string) error {
+	if err := client.record("RenameSession", target, name); err != nil {
+		return err
+	}
+	index, err := client.session(target)
+	if err != nil {
+		return err
+	}
+	if other, err := client.session(name); err == nil && other != index {
+		return fmt.Errorf("duplicate session: %s", name)
+	}
+	client.Entities.Sessions[index].name = name
+	return nil
+}
+
+func (client *FakeClient) RenameWindow(target string, name string) error {
+	if err := client.record("RenameWindow", target, name); err != nil {
+		return err
+	}
+	index, err := client.window(target)
+	if err != nil {
+		return err
+	}
+	client.Entities.Windows[index].name = name
+	return nil
+}
+
+func (client *FakeClient) KillSession(target string) error {
+	if err := client.record("KillSession", target); err != nil {
+		return err
+	}
+	index, err := client.session(target)
+	if err != nil {
+		return err
+	}
+	id := client.Entities.Sessions[index].id
+	client.Entities.Sessions = slices.Delete(client.Entities.Sessions, index, index+1)
+	for _, window := range slices.Clone(client.Entities.Windows) {
+		if window.parent == id {
+			client.removeWindow(window.id)
+		}
+	}
+	return nil
+}
+
+func (client *FakeClient) KillWindow(target string) error {
+	if err := client.record("KillWindow", target); err != nil {
+		return err
+	}
+	index, err := client.window(target)
+	if err != nil {
+		return err
+	}
+	window := client.Entities.Windows[index]
+	client.removeWindow(window.id)
+	client.pruneSession(window.parent)
+	return nil
+}
+
+func (client *FakeClient) KillPane(target string) error {
+	if err := client.record("KillPane", target); err != nil {
+		return err
+	}
+	index, err := client.pane(target)
+	if err != nil {
+		return err
+	}
+	pane := client.Entities.Panes[index]
+	client.Entities.Panes = slices.Delete(client.Entities.Panes, index, index+1)
+	delete(client.Contents, pane.id)
+	client.pruneWindow(pane.parent)
+	return nil
+}
+
+// pruneWindow deletes a window that has no panes left, like tmux does.
+func (client *FakeClient) pruneWindow(id int) {
+	if !slices.ContainsFunc(client.Entities.Panes, func(p TmuxEntity) bool { return p.parent == id }) {
+		windowIndex := findEntity(client.Entities.Windows, "@", windowTarget(id))
+		if windowIndex != -1 {
+			session := client.Entities.Windows[windowIndex].parent
+			client.removeWindow(id)
+			client.pruneSession(session)
+		}
+	}
+}
+
+// removeWindow deletes a window and its panes.
+func (client *FakeClient) removeWindow(id int) {
+	client.Entities.Windows = slices.DeleteFunc(client.Entities.Windows, func(w TmuxEntity) bool { return w.id == id })
+	client.Entities.Panes = slices.DeleteFunc(client.Entities.Panes, func(p TmuxEntity) bool {
+		if p.parent == id {
+			delete(client.Contents, p.id)
+			return true
+		}
+		return false
+	})
+}
+
+// pruneSession deletes a session that has no windows left, like tmux does.
+func (client *FakeClient) pruneSession(id int) {
+	if !slices.ContainsFunc(client.Entities.Windows, func(w TmuxEntity) bool { return w.parent == id }) {
+		client.Entities.Sessions = slices.DeleteFunc(client.Entities.Sessions, func(s TmuxEntity) bool { return s.id == id })
+	}
+}
+
+func (client *FakeClient) SwapWindows(src string, dst string) error {
+	if err := client.record("SwapWindows", src, dst); err != nil {
+		return err
+	}
+	a, err := client.window(src)
+	if err != nil {
+		return err
+	}
+	b, err := client.window(dst)
+	if err != nil {
+		return err
+	}
+	windows := client.Entities.Windows
+	windows[a], windows[b] = windows[b], windows[a]
+	windows[a].parent, windows[b].parent = windows[b].parent, windows[a].parent
+	windows[a].window.Index, windows[b].window.Index = windows[b].window.Index, windows[a].window.Index
+	return nil
+}
+
+func (client *FakeClient) RenumberWindows(session string) error {
+	if err := client.record("RenumberWindows", session); err != nil {
+		return err
+	}
+	index, err := client.session(session)
+	if err != nil {
+		return err
+	}
+	id := client.Entities.Sessions[index].id
+	windows := []*TmuxEntity{}
+	for i := range client.Entities.Windows {
+		if client.Entities.Windows[i].parent == id {
+			windows = append(windows, &client.Entities.Windows[i])
+		}
+	}
+	slices.SortFunc(windows, func(a, b *TmuxEntity) int { return a.window.Index - b.window.Index })
+	for i, window := range windows {
+		window.window.Index = i
+	}
+	return nil
+}
+
+func (client *FakeClient) SwapPanes(src string, dst string) error {
+	if err := client.record("SwapPanes", src, dst); err != nil {
+		return err
+	}
+	a, err := client.pane(src)
+	if err != nil {
+		return err
+	}
+	b, err := client.pane(dst)
+	if err != nil {
+		return err
+	}
+	panes := client.Entities.Panes
+	panes[a], panes[b] = panes[b], panes[a]
+	panes[a].parent, panes[b].parent = panes[b].parent, panes[a].parent
+	return nil
+}
+
+func (client *FakeClient) MoveWindow(src string, dst string, link bool) error {
+	if err := client.record("MoveWindow", src, dst, strconv.FormatBool(link)); err != nil {
+		return err
+	}
+	index, err := client.window(src)
+	if err != nil {
+		return err
+	}
+	dst, at, hasIndex := strings.Cut(dst, ":")
+	session, err := client.session(dst)
+	if err != nil {
+		return err
+	}
+	if hasIndex && client.indexInUse(client.Entities.Sessions[session].id, at) {
+		return fmt.Errorf("index in use: %s", at)
+	}
+	if link {
+		// Listed in its first session only, like ListEntities does
+		client.Entities.Windows[index].window.Linked = true
+		return nil
+	}
+	window := &client.Entities.Windows[index]
+	previous := window.parent
+	window.parent = client.Entities.Sessions[session].id
+	window.window.Index = 0
+	for _, w := range client.Entities.Windows {
+		if w.parent == window.parent && w.id != window.id {
+			window.window.Index = max(window.window.Index, w.window.Index+1)
+		}
+	}
+	if hasIndex {
+		window.window.Index, _ = strconv.Atoi(at)
+	}
+	client.pruneSession(previous)
+	return nil
+}
+
+// indexInUse tells whether a window of session is at index.
+func (client *FakeClient) indexInUse(session int, index string) bool {
+	return slices.ContainsFunc(client.Entities.Windows, func(w TmuxEntity) bool {
+		return w.parent == session && strconv.Itoa(w.window.Index) == index
+	})
+}
+
+func (client *FakeClient) JoinPane(src string, dst string, horizontal bool) error {
+	if err := client.record("JoinPane", src, dst, strconv.FormatBool(horizontal)); err != nil {
+		return err
+	}
+	index, err := client.pane(src)
+	if err != nil {
+		return err
+	}
+	window := -1
+	if w, err := client.window(dst); err == nil {
+		window = client.Entities.Windows[w].id
+	} else if p, err := client.pane(dst); err == nil {
+		window = client.Entities.Panes[p].parent
+	} else {
+		return err
+	}
+	previous := client.Entities.Panes[index].parent
+	client.Entities.Panes[index].parent = window
+	client.pruneWindow(previous)
+	return nil
+}
+
+func (client *FakeClient) BreakPane(src string, dst string) (Created, error) {
+	if err := client.record("BreakPane", src, dst); err != nil {
+		return Created{}, err
+	}
+	index, err := client.pane(src)
+	if err != nil {
+		return Created{}, err
+	}
+	sessionIndex, err := client.session(dst)
+	if err != nil {
+		return Created{}, err
+	}
+	pane := client.Entities.Panes[index]
+	session := client.Entities.Sessions[sessionIndex].id
+	window, placeholder := client.AddWindow(session, pane.name)
+	client.Entities.Panes = slices.DeleteFunc(client.Entities.Panes, func(p TmuxEntity) bool { return p.id == placeholder })
+	client.Entities.Panes[findEntity(client.Entities.Panes, "%", src)].parent = window
+	client.pruneWindow(pane.parent)
+	return Created{session, window, pane.id}, nil
+}
+
+func (client *FakeClient) DescendantCounts(pids []int) (map[int]int, error) {
+	if err := client.record("DescendantCounts"); err != nil {
+		return nil, err
+	}
+	counts := map[int]int{}
+	for _, pid := range pids {
+		counts[pid] = client.Descendants[pid]
+	}
+	return counts, nil
+}
+
+func (client *FakeClient) CommandLines(pids []int) (map[int]string, error) {
+	if err := client.record("CommandLines"); err != nil {
+		return nil, err
+	}
+	commands := map[int]string{}
+	for _, pid := range pids {
+		if command, ok := client.Foreground[pid]; ok {
+			commands[pid] = command
+		}
+	}
+	return commands, nil
+}
+
+func (client *FakeClient) Subscribe() (Subscription, error) {
+	if err := client.record("Subscribe"); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("control mode is not supported by the fake client")
+}
@@ -0,0 +1,48 @@
+package tmux_tui
+
+import (
+	"strings"
+	"testing"
+)
+
+// entityLine is a line of ListEntities' output for pane in window in
+// session, with the window linked or not.
+func entityLine(session string, window string, pane string, linked string) string {
+	parts := make([]string, 30)
+	parts[0], parts[1], parts[2], parts[3] = "P", session, window, pane
+	parts[4], parts[8] = "name"+session, "window"+window
+	parts[29] = linked
+	return strings.Join(parts, "\t")
+}
+
+func TestParseLinkedWindows(t *testing.T) {
+	output := strings.Join([]string{
+		entityLine("$0", "@0", "%0", "1"),
+		entityLine("$0", "@1", "%1", "0"),
+		entityLine("$1", "@2", "%2", "0"),
+		entityLine("$1", "@0", "%0", "1"),
+	}, "\n")
+
+	entities := parseEntities(output)
+
+	if len(entities.Sessions) != 2 || len(entities.Windows) != 3 || len(entities.Panes) != 3 {
+		t.Fatalf("parsed %d sessions, %d windows and %d panes, expected 2, 3 and 3", len(entities.Sessions), len(entities.Windows), len(entities.Panes))
+	}
+	window := entities.Windows[0]
+	if window.id != 0 || window.parent != 0 || !window.window.Linked {
+		t.Errorf("linked window is %d in %d (linked %t), expected 0 in its first session 0", window.id, window.parent, window.window.Linked)
+	}
+}
+
+func TestIsNoServer(t *testing.T) {
+	for stderr, expected := range map[string]bool{
+		"no server running on /tmp/tmux-1000/default":                            true,
+		"error connecting to /tmp/tmux-1000/default (No such file or directory)": true,
+		"error connecting to /tmp/tmux-1000/default (Permission denied)":         false,
+		"server exited unexpectedly":                                             false,
+	} {
+		if isNoServer(&TmuxError{Stderr: stderr}) != expected {
+			t.Errorf("isNoServer(%q) is %v", stderr, !expected)
+		}
+	}
+}
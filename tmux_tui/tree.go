@@ -0,0 +1,245 @@
+package tmux_tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// treeNode is a row of the tree: a session, window or pane, which is frame
+// 1, 2 or 3 of the lists.
+type treeNode struct {
+	frame       int
+	entity      TmuxEntity
+	depth       int
+	collapsed   bool
+	hasChildren bool
+	positions   []int
+}
+
+func treeKey(frame int, id int) string {
+	return fmt.Sprintf("%d:%d", frame, id)
+}
+
+// children are the windows of a session or the panes of a window.
+func (m AppModel) children(frame int, id int) []TmuxEntity {
+	var items []TmuxEntity
+	switch frame {
+	case 1:
+		items = m.windows.items
+	case 2:
+		items = m.panes.items
+	}
+	children := []TmuxEntity{}
+	for _, item := range items {
+		if item.parent == id {
+			children = append(children, item)
+		}
+	}
+	return children
+}
+
+// treeRows are the rows of the tree that are shown. While filtering, a node
+// is shown if it passes the filter of its list and either matches the
+// unscoped terms or has a descendant that does, and collapsed nodes are
+// expanded.
+func (m AppModel) treeRows() []treeNode {
+	rows := []treeNode{}
+	for _, session := range m.sessions.items {
+		if branch, ok := m.treeBranch(1, session, 0); ok {
+			rows = append(rows, branch...)
+		}
+	}
+	return rows
+}
+
+func (m AppModel) treeBranch(frame int, entity TmuxEntity, depth int) ([]treeNode, bool) {
+	filters := m.filters()
+
+	own, ok := matchFilter(filters[frame], entity)
+	if !ok {
+		return nil, false
+	}
+	unscoped, matched := matchFilter(m.treeFilter, entity)
+
+	children := m.children(frame, entity.id)
+	childRows := []treeNode{}
+	shown := 0
+	for _, child := range children {
+		if rows, ok := m.treeBranch(frame+1, child, depth+1); ok {
+			childRows = append(childRows, rows...)
+			shown++
+		}
+	}
+
+	// The filters of the lists below need something to match
+	for deeper := frame + 1; deeper <= 3; deeper++ {
+		if len(filters[deeper]) > 0 && shown == 0 {
+			return nil, false
+		}
+	}
+	if !matched && shown == 0 {
+		return nil, false
+	}
+
+	node := treeNode{
+		frame:       frame,
+		entity:      entity,
+		depth:       depth,
+		collapsed:   !m.hasFilters() && m.collapsed[treeKey(frame, entity.id)],
+		hasChildren: len(children) > 0,
+	}
+	if matched {
+		node.positions = append(own.positions, unscoped.positions...)
+	}
+
+	rows := []treeNode{node}
+	if !node.collapsed {
+		rows = append(rows, childRows...)
+	}
+	return rows, true
+}
+
+// treeCursor is the index of the row of the selected item of the focused
+// list, which is the node under the cursor, or -1 if it is not shown.
+func (m AppModel) treeCursor(rows []treeNode) int {
+	id := m.focusedList().currentId
+	return slices.IndexFunc(rows, func(node treeNode) bool {
+		return node.frame == m.focusedFrame && node.entity.id == id
+	})
+}
+
+// selectNode puts the cursor on node, selecting it and its ancestors in the
+// lists and focusing the list it belongs to, so that every action applies to
+// it.
+func (m *AppModel) selectNode(node treeNode) {
+	switch node.frame {
+	case 1:
+		m.sessions.currentId = node.entity.id
+	case 2:
+		m.sessions.currentId = node.entity.parent
+		m.windows.currentId = node.entity.id
+	case 3:
+		if window := m.windows.ItemWithId(node.entity.parent); window != nil {
+			m.sessions.currentId = window.parent
+		}
+		m.windows.currentId = node.entity.parent
+		m.panes.currentId = node.entity.id
+	}
+	m.focus(node.frame)
+}
+
+// moveTreeCursor moves the cursor delta rows.
+func (m *AppModel) moveTreeCursor(delta int) {
+	rows := m.treeRows()
+	if m.swapSrc != -1 {
+		// Swapping is between items of the same list
+		rows = slices.DeleteFunc(rows, func(node treeNode) bool { return node.frame != m.focusedFrame })
+	}
+	if len(rows) == 0 {
+		return
+	}
+	cursor := max(0, min(len(rows)-1, m.treeCursor(rows)+delta))
+	m.selectNode(rows[cursor])
+}
+
+// setCollapsed collapses or expands the node under the cursor. Collapsing a
+// node without children collapses its parent instead.
+func (m *AppModel) setCollapsed(collapsed bool) {
+	rows := m.treeRows()
+	cursor := m.treeCursor(rows)
+	if cursor == -1 {
+		return
+	}
+	node := rows[cursor]
+	if collapsed && (!node.hasChildren || node.collapsed) && node.depth > 0 {
+		for i := cursor - 1; i >= 0; i-- {
+			if rows[i].depth < node.depth {
+				node = rows[i]
+				m.selectNode(node)
+				break
+			}
+		}
+	}
+	m.collapsed[treeKey(node.frame, node.entity.id)] = collapsed
+}
+
+// keepTreeCursor moves the cursor to the closest ancestor that is shown, or
+// the first row, when the node under it is hidden by the filter.
+func (m *AppModel) keepTreeCursor() {
+	rows := m.treeRows()
+	if len(rows) == 0 || m.treeCursor(rows) != -1 {
+		return
+	}
+	for frame := m.focusedFrame - 1; frame >= 1; frame-- {
+		m.focus(frame)
+		if m.treeCursor(rows) != -1 {
+			return
+		}
+	}
+	m.selectNode(rows[0])
+}
+
+// treeOffset is the first row shown in a tree of height rows, enough to keep
+// the cursor visible.
+func treeOffset(cursor int, height int) int {
+	return max(0, cursor-height+1)
+}
+
+// treeHeight is how many rows fit in the tree.
+func (m AppModel) treeHeight() int {
+	return max(1, m.gridLayout().list(treeFrame).height-2)
+}
+
+// TreeFrame draws the sessions, windows and panes as a tree, with the node
+// under the cursor highlighted.
+func (m AppModel) TreeFrame() Frame {
+	itemStyle := lipgloss.NewStyle().Foreground(m.theme.Foreground).Background(m.theme.Background)
+	cursorStyle := itemStyle.Foreground(m.theme.Accent)
+	badgeStyle := itemStyle.Foreground(m.theme.Secondary)
+
+	frame := Frame{title: filterTitle("Tree", formatFilterQuery(m.filters(), 0)), focused: true}
+	if m.swapSrc != -1 || len(m.moveSrcs) > 0 {
+		frame.title = "Tree"
+	}
+
+	rows := m.treeRows()
+	cursor := m.treeCursor(rows)
+	height := m.treeHeight()
+	offset := treeOffset(cursor, height)
+
+	lines := []string{}
+	for i := offset; i < min(len(rows), offset+height); i++ {
+		node := rows[i]
+		nameStyle := itemStyle
+		if m.list(node.frame).IsMarked(node.entity.id) {
+			nameStyle = badgeStyle
+		}
+
+		prefix := "  "
+		if i == cursor {
+			prefix = "→ "
+		}
+		expander := "  "
+		if node.hasChildren && node.collapsed {
+			expander = "▸ "
+		} else if node.hasChildren {
+			expander = "▾ "
+		}
+
+		line := cursorStyle.Render(prefix) + itemStyle.Render(strings.Repeat("  ", node.depth)+expander)
+		line += nameStyle.Render(fmt.Sprintf("[%d]: ", node.entity.Label()))
+		line += highlight(node.entity.name, node.positions, nameStyle, nameStyle.Foreground(m.theme.Accent).Bold(true))
+		for _, badge := range node.entity.Badges() {
+			line += itemStyle.Render(" ") + badgeStyle.Render(badge)
+		}
+		lines = append(lines, line)
+	}
+
+	frame.contents = strings.Join(lines, "\n")
+	frame.above = offset
+	frame.below = max(0, len(rows)-offset-height)
+	return frame
+}
@@ -0,0 +1,233 @@
+package tmux_tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	// How many deletes can be undone.
+	maxUndo = 20
+	// Lines of scrollback kept for each deleted pane.
+	undoHistory = 1000
+)
+
+// undoEntry holds what a delete removed, so that it can be recreated. Panes
+// come back with the same directory, command and contents, but as new
+// processes.
+type undoEntry struct {
+	description string
+	sessions    []sessionUndo
+	windows     []windowUndo
+	panes       []paneUndo
+}
+
+// Each part of an undo entry knows the deleted ids that removed it, which
+// may be its own or those of all its children.
+type sessionUndo struct {
+	deleted []int
+	session SessionSnapshot
+}
+
+type windowUndo struct {
+	deleted []int
+	session int
+	window  WindowSnapshot
+}
+
+type paneUndo struct {
+	deleted []int
+	window  int
+	// layout of the window before the pane was removed
+	layout string
+	pane   PaneSnapshot
+}
+
+// captureUndo records the entities with the given ids, all of the kind shown
+// in frame, before they are deleted. Deleting every pane of a window deletes
+// the window too, and so on, so those are recorded as a whole.
+func captureUndo(client TmuxClient, frame int, ids []int) (undoEntry, error) {
+	entry := undoEntry{}
+
+	entities, err := client.ListEntities()
+	if err != nil {
+		return entry, err
+	}
+
+	commands, err := paneCommands(client, entities.Panes)
+	if err != nil {
+		return entry, err
+	}
+
+	find := func(items []TmuxEntity, id int) *TmuxEntity {
+		index := slices.IndexFunc(items, func(e TmuxEntity) bool { return e.id == id })
+		if index == -1 {
+			return nil
+		}
+		return &items[index]
+	}
+
+	// Whether ids has every child of parent
+	coversAll := func(items []TmuxEntity, parent int, ids []int) bool {
+		for _, item := range items {
+			if item.parent == parent && !slices.Contains(ids, item.id) {
+				return false
+			}
+		}
+		return true
+	}
+
+	sessions := []int{}
+	windows := []int{}
+	panes := []int{}
+	// The deleted ids that remove each session and window
+	sessionsDeleted := map[int][]int{}
+	windowsDeleted := map[int][]int{}
+	switch frame {
+	case 1:
+		sessions = ids
+		for _, id := range ids {
+			sessionsDeleted[id] = []int{id}
+		}
+	case 2:
+		windows = ids
+		for _, id := range ids {
+			windowsDeleted[id] = []int{id}
+		}
+	case 3:
+		panes = ids
+	}
+
+	for _, id := range panes {
+		pane := find(entities.Panes, id)
+		if pane == nil {
+			continue
+		}
+		if coversAll(entities.Panes, pane.parent, panes) {
+			if !slices.Contains(windows, pane.parent) {
+				windows = append(windows, pane.parent)
+			}
+			windowsDeleted[pane.parent] = append(windowsDeleted[pane.parent], id)
+			continue
+		}
+		window := find(entities.Windows, pane.parent)
+		snapshot, err := snapshotPane(client, commands, *pane, undoHistory)
+		if err != nil {
+			return entry, err
+		}
+		entry.panes = append(entry.panes, paneUndo{[]int{id}, window.id, window.window.Layout, snapshot})
+	}
+
+	for _, id := range windows {
+		window := find(entities.Windows, id)
+		if window == nil {
+			continue
+		}
+		if coversAll(entities.Windows, window.parent, windows) {
+			if !slices.Contains(sessions, window.parent) {
+				sessions = append(sessions, window.parent)
+			}
+			sessionsDeleted[window.parent] = append(sessionsDeleted[window.parent], windowsDeleted[id]...)
+			continue
+		}
+		snapshot, err := snapshotWindow(client, entities, commands, *window, undoHistory)
+		if err != nil {
+			return entry, err
+		}
+		entry.windows = append(entry.windows, windowUndo{windowsDeleted[id], window.parent, snapshot})
+	}
+
+	for _, id := range sessions {
+		session := find(entities.Sessions, id)
+		if session == nil {
+			continue
+		}
+		snapshot, err := snapshotSession(client, entities, commands, *session, undoHistory)
+		if err != nil {
+			return entry, err
+		}
+		entry.sessions = append(entry.sessions, sessionUndo{sessionsDeleted[id], snapshot})
+	}
+
+	return entry, nil
+}
+
+// only keeps what was removed by deleting killed alone, leaving out what the
+// failed deletions of other ids left in place.
+func (entry undoEntry) only(killed []int) undoEntry {
+	removed := func(deleted []int) bool {
+		for _, id := range deleted {
+			if !slices.Contains(killed, id) {
+				return false
+			}
+		}
+		return true
+	}
+
+	kept := undoEntry{description: entry.description}
+	for _, session := range entry.sessions {
+		if removed(session.deleted) {
+			kept.sessions = append(kept.sessions, session)
+		}
+	}
+	for _, window := range entry.windows {
+		if removed(window.deleted) {
+			kept.windows = append(kept.windows, window)
+		}
+	}
+	for _, pane := range entry.panes {
+		if removed(pane.deleted) {
+			kept.panes = append(kept.panes, pane)
+		}
+	}
+	return kept
+}
+
+// restore recreates what was deleted, as well as it can.
+func (entry undoEntry) restore(client TmuxClient) error {
+	errs := []error{}
+
+	for _, session := range entry.sessions {
+		if err := restoreSession(client, session.session); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", session.session.Name, err))
+		}
+	}
+
+	for _, window := range entry.windows {
+		if _, err := restoreWindow(client, window.session, "", window.window); err != nil {
+			errs = append(errs, fmt.Errorf("window %s: %w", window.window.Name, err))
+		}
+	}
+
+	for _, pane := range entry.panes {
+		entities, err := client.ListEntities()
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		neighbour := slices.IndexFunc(entities.Panes, func(p TmuxEntity) bool { return p.parent == pane.window })
+		if neighbour == -1 {
+			errs = append(errs, fmt.Errorf("pane %s: its window is gone", pane.pane.Command))
+			continue
+		}
+		id, err := restorePane(client, entities.Panes[neighbour].id, pane.pane)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pane %s: %w", pane.pane.Command, err))
+			continue
+		}
+		// Only fits if the other panes are still the same, so failing is fine
+		client.SelectLayout(windowTarget(pane.window), pane.layout)
+		if pane.pane.Active {
+			if err := client.SelectPane(paneTarget(id)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if !isShell(pane.pane.Command) {
+			if err := runInPane(client, id, pane.pane.Command); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -1,8 +1,8 @@
 package tmux_tui
 
 import (
-	"fmt"
-	"os/exec"
+	"errors"
+	"slices"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -10,49 +10,90 @@ import (
 func goToWindowCmd(m AppModel) tea.Cmd {
 	return func() tea.Msg {
 		window := m.windows.ItemWithId(m.windows.currentId)
-		c := exec.Command("tmux",
-			"switch-client", "-t", fmt.Sprintf("$%d", window.parent), ";",
-			"select-window", "-t", fmt.Sprintf("@%d", window.id))
-		c.Run()
-		return tea.QuitMsg{}
+		if window == nil {
+			return nil
+		}
+		if err := m.client.SwitchClient(sessionTarget(window.parent)); err != nil {
+			return errorMsg{err}
+		}
+		err := m.client.SelectWindow(windowTarget(window.id))
+		return resultMsg(err, tea.QuitMsg{})
 	}
 }
 
 func renameWindowCmd(m AppModel) tea.Cmd {
 	return func() tea.Msg {
-		c := exec.Command("tmux", "rename-window", "-t", fmt.Sprintf("@%d", m.windows.currentId), m.textInput.Value())
-		c.Run()
-		return clearInputTextMsg{}
+		err := m.client.RenameWindow(windowTarget(m.windows.currentId), m.textInput.Value())
+		return resultMsg(err, clearInputTextMsg{})
 	}
 }
 
-func newWindowCmd(m AppModel) tea.Cmd {
+// newWindowCmd creates a window named name that starts in directory, in the
+// selected session.
+func newWindowCmd(m AppModel, name string, directory string) tea.Cmd {
 	return func() tea.Msg {
-		if len(m.textInput.Value()) > 0 {
-			c := exec.Command("tmux", "new-window", "-n", m.textInput.Value(), "-t", fmt.Sprintf("$%d:", m.sessions.currentId))
-			c.Run()
-		} else {
-			c := exec.Command("tmux", "new-window", "-t", fmt.Sprintf("$%d:", m.sessions.currentId))
-			c.Run()
+		_, err := m.client.NewWindow(NewWindowOptions{
+			Session:   sessionTarget(m.sessions.currentId),
+			Name:      name,
+			Directory: directory,
+		})
+		if err == nil && len(directory) > 0 {
+			err = VisitDirectory(directory)
 		}
-		return clearInputTextMsg{}
+		return resultMsg(err, clearInputTextMsg{})
 	}
 }
 
-func deleteWindowCmd(m AppModel) tea.Cmd {
+func swapWindowsCmd(m AppModel, src int) tea.Cmd {
 	return func() tea.Msg {
-		c := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("@%d", m.windows.currentId))
-		c.Run()
-		return tickMsg{}
+		err := m.client.SwapWindows(windowTarget(src), windowTarget(m.windows.currentId))
+		return resultMsg(err, refreshMsg{})
 	}
 }
 
-func swapWindowsCmd(m AppModel, src int) tea.Cmd {
+// moveWindowCmd moves, or links, windows srcs to the selected session.
+func moveWindowCmd(m AppModel, srcs []int, link bool) tea.Cmd {
+	return func() tea.Msg {
+		errs := []error{}
+		for _, src := range srcs {
+			errs = append(errs, m.client.MoveWindow(windowTarget(src), sessionTarget(m.sessions.currentId), link))
+		}
+		return resultMsg(errors.Join(errs...), refreshMsg{})
+	}
+}
+
+// shiftWindowCmd swaps the selected window with the one before it in its
+// session (offset -1) or after it (offset 1).
+func shiftWindowCmd(m AppModel, offset int) tea.Cmd {
+	window := m.windows.ItemWithId(m.windows.currentId)
+	if window == nil {
+		return nil
+	}
+
+	siblings := slices.DeleteFunc(slices.Clone(m.windows.items), func(w TmuxEntity) bool {
+		return w.parent != window.parent
+	})
+	index := slices.IndexFunc(siblings, func(w TmuxEntity) bool { return w.id == window.id })
+	if index+offset < 0 || index+offset >= len(siblings) {
+		return nil
+	}
+	neighbour := siblings[index+offset]
+
+	return func() tea.Msg {
+		err := m.client.SwapWindows(windowTarget(window.id), windowTarget(neighbour.id))
+		return resultMsg(err, refreshMsg{})
+	}
+}
+
+// renumberWindowsCmd closes the index gaps in the selected session or, from
+// the windows frame, in the session of the selected window.
+func renumberWindowsCmd(m AppModel) tea.Cmd {
+	session := m.sessions.currentId
+	if window := m.windows.ItemWithId(m.windows.currentId); m.focusedFrame == 2 && window != nil {
+		session = window.parent
+	}
 	return func() tea.Msg {
-		src := fmt.Sprintf("@%d", src)
-		dst := fmt.Sprintf("@%d", m.windows.currentId)
-		c := exec.Command("tmux", "swap-window", "-s", src, "-t", dst)
-		c.Run()
-		return tickMsg{}
+		err := m.client.RenumberWindows(sessionTarget(session))
+		return resultMsg(err, refreshMsg{})
 	}
 }